@@ -1,19 +1,78 @@
 package config
 
+// message ids, the text of each locale is in config/locales/<locale>.json
 var (
-	REQUEST_FAILED             = "Required field cannot be empty OR Invalid input"
-	APPLICANT_SUBMIT_SUCCESS   = "Applicant submitted successfully"
-	SCHEME_SUBMIT_SUCCESS      = "Scheme submitted successfully"
-	APPLICATION_SUBMIT_SUCCESS = "Application submitted successfully"
-	NO_ELIGIBLE_SCHEME         = "No eligible scheme for this applicant"
-	APPLICANT_UPDATE_SUCCESS   = "Applicant updated successfully"
-	SCHEME_UPDATE_SUCCESS      = "Scheme upated successfully"
-	APPLICANT_ID_EMPTY         = "Applicant Id cannot be empty"
-	APPLICATION_ID_EMPTY       = "Application Id cannot be empty"
-	SCHEME_ID_EMPTY            = "Scheme Id cannot be empty"
-	INVALID_APPLICANT_ID       = "Invalid applicant Id"
-	INVALID_APPLICATION_ID     = "Invalid application Id"
-	INVALID_SCHEME_ID          = "Invalid scheme Id"
-	APPLICANT_DELETE_SUCCESS   = "Applicant deleted successfully"
-	APPLICANT_NOT_FOUND        = "Applicant not found"
+	REQUEST_FAILED                  = "request_failed"
+	APPLICANT_SUBMIT_SUCCESS        = "applicant_submit_success"
+	SCHEME_SUBMIT_SUCCESS           = "scheme_submit_success"
+	APPLICATION_SUBMIT_SUCCESS      = "application_submit_success"
+	NO_ELIGIBLE_SCHEME              = "no_eligible_scheme"
+	APPLICANT_UPDATE_SUCCESS        = "applicant_update_success"
+	SCHEME_UPDATE_SUCCESS           = "scheme_update_success"
+	APPLICANT_ID_EMPTY              = "applicant_id_empty"
+	APPLICATION_ID_EMPTY            = "application_id_empty"
+	SCHEME_ID_EMPTY                 = "scheme_id_empty"
+	INVALID_APPLICANT_ID            = "invalid_applicant_id"
+	INVALID_APPLICATION_ID          = "invalid_application_id"
+	INVALID_SCHEME_ID               = "invalid_scheme_id"
+	APPLICANT_DELETE_SUCCESS        = "applicant_delete_success"
+	APPLICANT_NOT_FOUND             = "applicant_not_found"
+	SCHEME_NOT_FOUND                = "scheme_not_found"
+	EDUCATION_LEVEL_SUBMIT_SUCCESS  = "education_level_submit_success"
+	EDUCATION_LEVEL_UPDATE_SUCCESS  = "education_level_update_success"
+	EDUCATION_LEVEL_DELETE_SUCCESS  = "education_level_delete_success"
+	EDUCATION_LEVEL_ID_EMPTY        = "education_level_id_empty"
+	INVALID_EDUCATION_LEVEL_ID      = "invalid_education_level_id"
+	INVALID_EVALUATION_DATE         = "invalid_evaluation_date"
+	INTERNAL_ERROR                  = "internal_error"
+	INVALID_REQUEST_BODY            = "invalid_request_body"
+	INVALID_LIST_QUERY              = "invalid_list_query"
+	APPLICATION_NOT_FOUND           = "application_not_found"
+	EDUCATION_LEVEL_NOT_FOUND       = "education_level_not_found"
+	UNKNOWN_EDUCATION_LEVEL         = "unknown_education_level"
+	APPLICANT_NOT_ELIGIBLE          = "applicant_not_eligible"
+	DUPLICATE_APPLICATION           = "duplicate_application"
+	INVALID_STATUS_TRANSITION       = "invalid_status_transition"
+	APPLICATION_DELETE_SUCCESS      = "application_delete_success"
+	SCHEME_DELETE_SUCCESS           = "scheme_delete_success"
+	HOUSEHOLD_MEMBER_ID_EMPTY       = "household_member_id_empty"
+	INVALID_HOUSEHOLD_MEMBER_ID     = "invalid_household_member_id"
+	HOUSEHOLD_MEMBER_NOT_FOUND      = "household_member_not_found"
+	HOUSEHOLD_MEMBER_SUBMIT_SUCCESS = "household_member_submit_success"
+	HOUSEHOLD_MEMBER_UPDATE_SUCCESS = "household_member_update_success"
+	HOUSEHOLD_MEMBER_DELETE_SUCCESS = "household_member_delete_success"
+	PRECONDITION_REQUIRED           = "precondition_required"
+	PRECONDITION_FAILED             = "precondition_failed"
+	INVALID_IF_MATCH                = "invalid_if_match"
+	SCHEME_VERSION_NOT_FOUND        = "scheme_version_not_found"
+	SCHEME_DRAFT_NOT_FOUND          = "scheme_draft_not_found"
+	INVALID_SCHEME_VERSION          = "invalid_scheme_version"
+	SCHEME_DRAFT_SAVE_SUCCESS       = "scheme_draft_save_success"
+	SCHEME_DRAFT_DELETE_SUCCESS     = "scheme_draft_delete_success"
+	SCHEME_PUBLISH_SUCCESS          = "scheme_publish_success"
+	SCHEME_APPLICATIONS_CLOSED      = "scheme_applications_closed"
+	SCHEME_BUDGET_EXCEEDED          = "scheme_budget_exceeded"
+	APPLICATION_WAITLISTED          = "application_waitlisted"
+	DISBURSEMENTS_PAUSE_SUCCESS     = "disbursements_pause_success"
+	DISBURSEMENTS_RESUME_SUCCESS    = "disbursements_resume_success"
+	DISBURSEMENTS_CANCEL_SUCCESS    = "disbursements_cancel_success"
+	LEDGER_ENTRY_NOT_FOUND          = "ledger_entry_not_found"
+	DISBURSEMENT_NOT_FOUND          = "disbursement_not_found"
+	PAYOUT_NOT_ALLOWED              = "payout_not_allowed"
+	IDEMPOTENCY_KEY_REUSED          = "idempotency_key_reused"
+	IDEMPOTENCY_KEY_REQUIRED        = "idempotency_key_required"
+	APPLICATION_HAS_PAYOUTS         = "application_has_payouts"
+	LEDGER_ENTRY_ID_EMPTY           = "ledger_entry_id_empty"
+	INVALID_LEDGER_ENTRY_ID         = "invalid_ledger_entry_id"
+	INVALID_RECONCILIATION_PERIOD   = "invalid_reconciliation_period"
+	PAYOUT_POST_SUCCESS             = "payout_post_success"
+	PAYOUT_SETTLE_SUCCESS           = "payout_settle_success"
+	PAYOUT_REVERSE_SUCCESS          = "payout_reverse_success"
+	SCHEME_EXCLUSIVE_HELD           = "scheme_exclusive_held"
+	SCHEME_REQUIRED_NOT_HELD        = "scheme_required_not_held"
+	SCHEME_SUPERSEDED               = "scheme_superseded"
+	EDUCATION_LEVEL_IN_USE          = "education_level_in_use"
+	EDUCATION_LEVEL_EXISTS          = "education_level_exists"
+	SCHEME_NOT_ACTIVE               = "scheme_not_active"
+	LEDGER_UNBALANCED               = "ledger_unbalanced"
 )
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultLocale = "en"
+	MessagesDir   = "config/locales"
+)
+
+var SupportedLocales = []string{"en", "zh", "ms", "ta"}
+
+// message text by locale then message id, filled by LoadMessages
+var messages = map[string]map[string]string{}
+
+// LoadMessages reads <dir>/<locale>.json for every supported locale. The default locale must
+// have a file, the others fall back to it for missing messages.
+func LoadMessages(dir string) error {
+	catalogue := map[string]map[string]string{}
+	for _, locale := range SupportedLocales {
+		data, err := os.ReadFile(filepath.Join(dir, locale+".json"))
+		if os.IsNotExist(err) && locale != DefaultLocale {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %s messages: %v", locale, err)
+		}
+
+		var localeMessages map[string]string
+		if err := json.Unmarshal(data, &localeMessages); err != nil {
+			return fmt.Errorf("error parsing %s messages: %v", locale, err)
+		}
+		catalogue[locale] = localeMessages
+	}
+
+	messages = catalogue
+	return nil
+}
+
+// LookupMessage returns the text of a message id in locale, falling back to the default locale.
+func LookupMessage(locale string, id string) (string, bool) {
+	if text, ok := messages[locale][id]; ok {
+		return text, true
+	}
+	text, ok := messages[DefaultLocale][id]
+	return text, ok
+}
+
+// Message returns the text of a message id in locale, or the id itself when it has no text.
+func Message(locale string, id string) string {
+	if text, ok := LookupMessage(locale, id); ok {
+		return text
+	}
+	return id
+}
+
+// MatchLocale picks the supported locale preferred by an Accept-Language header,
+// e.g. "zh-CN,zh;q=0.9,en;q=0.8" gives "zh". Region subtags are ignored.
+func MatchLocale(acceptLanguage string) string {
+	type preference struct {
+		locale string
+		q      float64
+	}
+
+	preferences := []preference{}
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = value
+				}
+			}
+		}
+
+		locale := strings.SplitN(tag, "-", 2)[0]
+		if locale == "*" {
+			locale = DefaultLocale
+		}
+		preferences = append(preferences, preference{locale: locale, q: q})
+	}
+
+	// stable so equal weights keep the order of the header
+	sort.SliceStable(preferences, func(i, j int) bool {
+		return preferences[i].q > preferences[j].q
+	})
+
+	for _, p := range preferences {
+		if p.q <= 0 {
+			continue
+		}
+		for _, locale := range SupportedLocales {
+			if p.locale == locale {
+				return locale
+			}
+		}
+	}
+
+	return DefaultLocale
+}
@@ -8,4 +8,92 @@ const (
 	StatusCompleted  = "completed"
 	StatusOnHold     = "on hold"
 	StatusCancelled  = "cancelled"
+	StatusWaitlisted = "waitlisted"
+)
+
+// actor recorded for a status change when the request does not name one
+const (
+	ActorHeader  = "X-Actor"
+	ActorDefault = "system"
+)
+
+// application policies of a scheme
+const (
+	PolicyOneActive  = "one_active"
+	PolicyOnePerYear = "one_per_year"
+	PolicyUnlimited  = "unlimited"
+)
+
+// statuses of a scheme version
+const (
+	VersionDraft     = "draft"
+	VersionPublished = "published"
+	VersionRetired   = "retired"
+)
+
+// states of a scheme on a date, from its effective period
+const (
+	SchemeUpcoming = "upcoming"
+	SchemeActive   = "active"
+	SchemeExpired  = "expired"
+)
+
+// what happens to an approval over the budget or recipient quota of a scheme
+const (
+	BudgetBlock    = "block"
+	BudgetWaitlist = "waitlist"
+)
+
+// how often a benefit is paid
+const (
+	FrequencyOneOff    = "one_off"
+	FrequencyMonthly   = "monthly"
+	FrequencyQuarterly = "quarterly"
+	FrequencyYearly    = "yearly"
+)
+
+// when the first instalment of a benefit is due
+const (
+	StartOnApproval = "on_approval"
+	StartNextMonth  = "next_month"
+	StartFixedDate  = "fixed_date"
+)
+
+// statuses of a scheduled disbursement
+const (
+	DisbursementScheduled  = "scheduled"
+	DisbursementPaused     = "paused"
+	DisbursementCancelled  = "cancelled"
+	DisbursementProcessing = "processing"
+	DisbursementPaid       = "paid"
+	DisbursementFailed     = "failed"
+	DisbursementReversed   = "reversed"
+)
+
+// statuses of a ledger entry, a reversal entry is reversed and offsets the payout it reverses
+const (
+	LedgerScheduled = "scheduled"
+	LedgerPaid      = "paid"
+	LedgerFailed    = "failed"
+	LedgerReversed  = "reversed"
+)
+
+// accounts of the double-entry postings of a ledger entry, a paid payout moves its amount from the scheme
+// fund to the beneficiary and a reversal moves it back
+const (
+	AccountSchemeFund         = "scheme_fund"
+	AccountBeneficiaryPayable = "beneficiary_payable"
+)
+
+// sides of a ledger posting
+const (
+	SideDebit  = "debit"
+	SideCredit = "credit"
+)
+
+// how a scheme relates to another scheme an applicant holds
+const (
+	RelationExclusiveWith = "exclusive_with"
+	RelationRequires      = "requires"
+	RelationSupersedes    = "supersedes"
 )
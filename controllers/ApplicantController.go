@@ -17,14 +17,27 @@ type ApplicantController struct {
 
 // get all applicants
 func (ac *ApplicantController) GetAllApplicants(c *gin.Context) {
+	query, err := listQuery(c, "asc")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	filter := models.ApplicantFilter{
+		EmploymentStatus: c.Query("employment_status"),
+		MaritalStatus:    c.Query("marital_status"),
+		Name:             c.Query("name"),
+	}
+	filter.Apply(&query)
+
 	ctx := c.Request.Context()
 	applicant := models.Applicant{}
-	data, err := applicant.GetAllApplicants(ctx, ac.DB)
+	data, page, err := applicant.GetAllApplicants(ctx, ac.DB, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get applicants : " + err.Error()})
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"applicants": data})
+	c.JSON(http.StatusOK, gin.H{"applicants": data, "limit": page.Limit, "total": page.Total, "next_cursor": page.NextCursor})
 }
 
 // create applicant
@@ -32,44 +45,45 @@ func (ac *ApplicantController) CreateApplicant(c *gin.Context) {
 	ctx := c.Request.Context()
 	applicant := models.Applicant{}
 	if err := c.ShouldBind(&applicant); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create applicant : " + err.Error()})
+		respondError(c, invalidBody(err))
 		return
 	}
 
-	if formValidate := validator.ValidateApplicantForm(applicant); !formValidate {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create applicant : " + config.REQUEST_FAILED})
+	if fields := validator.ValidateApplicantForm(applicant); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
 		return
 	}
 
 	if err := applicant.CreateApplicant(ctx, ac.DB); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create applicant : " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": config.APPLICANT_SUBMIT_SUCCESS})
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.APPLICANT_SUBMIT_SUCCESS)})
 }
 
 // get applicant by ID
 func (ac *ApplicantController) GetApplicantByID(c *gin.Context) {
 	aid := c.Param("id")
 	if aid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get applicant : " + config.APPLICANT_ID_EMPTY})
+		respondError(c, invalidRequest(config.APPLICANT_ID_EMPTY))
 		return
 	}
 
 	ctx := c.Request.Context()
 	applicantId, err := uuid.Parse(aid)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get applicant : " + config.INVALID_APPLICANT_ID})
+		respondError(c, invalidRequest(config.INVALID_APPLICANT_ID))
 		return
 	}
 	applicant := models.Applicant{Id: applicantId}
 	err = applicant.GetApplicantById(ctx, ac.DB)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to create applicant : " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
+	setETag(c, applicant.Version)
 	c.JSON(http.StatusOK, gin.H{"applicant": applicant})
 }
 
@@ -77,68 +91,297 @@ func (ac *ApplicantController) GetApplicantByID(c *gin.Context) {
 func (ac *ApplicantController) UpdateApplicant(c *gin.Context) {
 	aid := c.Param("id")
 	if aid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update applicant : " + config.APPLICANT_ID_EMPTY})
+		respondError(c, invalidRequest(config.APPLICANT_ID_EMPTY))
 		return
 	}
 
 	ctx := c.Request.Context()
 	applicantId, err := uuid.Parse(aid)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update applicant : " + config.INVALID_APPLICANT_ID})
+		respondError(c, invalidRequest(config.INVALID_APPLICANT_ID))
 		return
 	}
-	applicant := models.Applicant{Id: applicantId}
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	applicant := models.Applicant{Id: applicantId, Version: version}
 	if err := c.ShouldBind(&applicant); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update applicant : " + err.Error()})
+		respondError(c, invalidBody(err))
 		return
 	}
 
-	if formValidate := validator.ValidateApplicantForm(applicant); !formValidate {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update applicant : " + config.REQUEST_FAILED})
+	if fields := validator.ValidateApplicantForm(applicant); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
 		return
 	}
 
 	// check does applicant Id exist
 	err = applicant.CheckApplicantExist(ctx, ac.DB)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to update applicant : " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	if err := applicant.UpdateApplicant(ctx, ac.DB); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update applicant : " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": config.APPLICANT_UPDATE_SUCCESS})
+	setETag(c, applicant.Version)
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.APPLICANT_UPDATE_SUCCESS)})
+}
+
+// patch applicant by ID with a JSON merge patch, household members are kept by their id
+func (ac *ApplicantController) PatchApplicant(c *gin.Context) {
+	aid := c.Param("id")
+	if aid == "" {
+		respondError(c, invalidRequest(config.APPLICANT_ID_EMPTY))
+		return
+	}
+
+	ctx := c.Request.Context()
+	applicantId, err := uuid.Parse(aid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_APPLICANT_ID))
+		return
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	current := models.Applicant{Id: applicantId}
+	if err := current.GetApplicantById(ctx, ac.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+	// the patch must be made against the version the client read
+	if err := models.CheckVersion(applicantId, current.Version, version); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var applicant models.Applicant
+	if err := mergePatch(c, current, &applicant); err != nil {
+		respondError(c, err)
+		return
+	}
+	// the id comes from the path, a patched id is ignored
+	applicant.Id = applicantId
+	applicant.Version = version
+
+	if fields := validator.ValidateApplicantForm(applicant); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
+		return
+	}
+
+	if err := applicant.UpdateApplicant(ctx, ac.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := applicant.GetApplicantById(ctx, ac.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, applicant.Version)
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.APPLICANT_UPDATE_SUCCESS), "applicant": applicant})
 }
 
 // delete applicant by Id
 func (ac *ApplicantController) DeleteApplicant(c *gin.Context) {
 	aid := c.Param("id")
 	if aid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete applicant : " + config.APPLICANT_ID_EMPTY})
+		respondError(c, invalidRequest(config.APPLICANT_ID_EMPTY))
 		return
 	}
 
 	applicantId, err := uuid.Parse(aid)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete applicant : " + config.INVALID_APPLICANT_ID})
+		respondError(c, invalidRequest(config.INVALID_APPLICANT_ID))
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	ctx := c.Request.Context()
 
-	applicant := models.Applicant{Id: applicantId}
+	applicant := models.Applicant{Id: applicantId, Version: version}
 	if err := applicant.CheckApplicantExist(ctx, ac.DB); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to delete applicant : " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	if err := applicant.DeleteApplicant(ctx, ac.DB); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete applicant: " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": config.APPLICANT_DELETE_SUCCESS})
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.APPLICANT_DELETE_SUCCESS)})
+}
+
+// get household members of an applicant
+func (ac *ApplicantController) GetHouseholdMembers(c *gin.Context) {
+	applicant, ok := ac.householdApplicant(c)
+	if !ok {
+		return
+	}
+
+	members, err := applicant.GetHouseholdMembers(c.Request.Context(), ac.DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"household": members})
+}
+
+// add a household member to an applicant
+func (ac *ApplicantController) AddHouseholdMember(c *gin.Context) {
+	applicant, ok := ac.householdApplicant(c)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	applicant.Version = version
+
+	member := models.HouseholdMember{}
+	if err := c.ShouldBind(&member); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	if fields := validator.ValidateHouseholdMember("", member); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
+		return
+	}
+
+	if err := applicant.AddHouseholdMember(c.Request.Context(), ac.DB, &member); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, applicant.Version)
+
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.HOUSEHOLD_MEMBER_SUBMIT_SUCCESS), "member": member})
+}
+
+// update a household member of an applicant
+func (ac *ApplicantController) UpdateHouseholdMember(c *gin.Context) {
+	applicant, ok := ac.householdApplicant(c)
+	if !ok {
+		return
+	}
+
+	memberId, ok := householdMemberId(c)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	applicant.Version = version
+
+	member := models.HouseholdMember{}
+	if err := c.ShouldBind(&member); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+	member.Id = memberId
+
+	if fields := validator.ValidateHouseholdMember("", member); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
+		return
+	}
+
+	if err := applicant.UpdateHouseholdMember(c.Request.Context(), ac.DB, &member); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, applicant.Version)
+
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.HOUSEHOLD_MEMBER_UPDATE_SUCCESS), "member": member})
+}
+
+// remove a household member from an applicant
+func (ac *ApplicantController) DeleteHouseholdMember(c *gin.Context) {
+	applicant, ok := ac.householdApplicant(c)
+	if !ok {
+		return
+	}
+
+	memberId, ok := householdMemberId(c)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	applicant.Version = version
+
+	if err := applicant.DeleteHouseholdMember(c.Request.Context(), ac.DB, memberId); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, applicant.Version)
+
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.HOUSEHOLD_MEMBER_DELETE_SUCCESS)})
+}
+
+// householdApplicant loads the applicant of a household route, writing the error response when it cannot.
+func (ac *ApplicantController) householdApplicant(c *gin.Context) (models.Applicant, bool) {
+	aid := c.Param("id")
+	if aid == "" {
+		respondError(c, invalidRequest(config.APPLICANT_ID_EMPTY))
+		return models.Applicant{}, false
+	}
+
+	applicantId, err := uuid.Parse(aid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_APPLICANT_ID))
+		return models.Applicant{}, false
+	}
+
+	applicant := models.Applicant{Id: applicantId}
+	if err := applicant.CheckApplicantExist(c.Request.Context(), ac.DB); err != nil {
+		respondError(c, err)
+		return models.Applicant{}, false
+	}
+
+	return applicant, true
+}
+
+func householdMemberId(c *gin.Context) (uuid.UUID, bool) {
+	mid := c.Param("memberId")
+	if mid == "" {
+		respondError(c, invalidRequest(config.HOUSEHOLD_MEMBER_ID_EMPTY))
+		return uuid.Nil, false
+	}
+
+	memberId, err := uuid.Parse(mid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_HOUSEHOLD_MEMBER_ID))
+		return uuid.Nil, false
+	}
+	return memberId, true
 }
@@ -6,6 +6,7 @@ import (
 	"oneCV/config"
 	"oneCV/models"
 	"oneCV/validator"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -17,14 +18,65 @@ type ApplicantionController struct {
 
 // get all applications
 func (ac *ApplicantionController) GetAllApplications(c *gin.Context) {
+	query, err := listQuery(c, "desc")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	filter := models.ApplicationFilter{Status: c.Query("status")}
+	if filter.SchemeId, err = queryId(c, "scheme_id"); err != nil {
+		respondError(c, err)
+		return
+	}
+	if filter.ApplicantId, err = queryId(c, "applicant_id"); err != nil {
+		respondError(c, err)
+		return
+	}
+	if filter.SubmittedFrom, err = queryDate(c, "submitted_from"); err != nil {
+		respondError(c, err)
+		return
+	}
+	if filter.SubmittedTo, err = queryDate(c, "submitted_to"); err != nil {
+		respondError(c, err)
+		return
+	}
+	filter.Apply(&query)
+
 	ctx := c.Request.Context()
 	application := models.Application{}
-	applications, err := application.GetAllApplications(ctx, ac.DB)
+	applications, page, err := application.GetAllApplications(ctx, ac.DB, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get applications : " + err.Error()})
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"applications": applications})
+	c.JSON(http.StatusOK, gin.H{"applications": applications, "limit": page.Limit, "total": page.Total, "next_cursor": page.NextCursor})
+}
+
+// get application by ID
+func (ac *ApplicantionController) GetApplicationByID(c *gin.Context) {
+	aid := c.Param("id")
+	if aid == "" {
+		respondError(c, invalidRequest(config.APPLICATION_ID_EMPTY))
+		return
+	}
+
+	applicationId, err := uuid.Parse(aid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_APPLICATION_ID))
+		return
+	}
+
+	ctx := c.Request.Context()
+	application := models.Application{Id: applicationId}
+	result, err := application.GetApplicationById(ctx, ac.DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, result.Version)
+	c.JSON(http.StatusOK, gin.H{"application": result})
 }
 
 // create applications
@@ -32,12 +84,12 @@ func (ac *ApplicantionController) CreateApplication(c *gin.Context) {
 	ctx := c.Request.Context()
 	applicationReq := models.ApplicationRequest{}
 	if err := c.ShouldBind(&applicationReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create application : " + err.Error()})
+		respondError(c, invalidBody(err))
 		return
 	}
 
-	if formValidate := validator.ValidateApplicationForm(applicationReq); !formValidate {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create application : " + config.REQUEST_FAILED})
+	if fields := validator.ValidateApplicationForm(applicationReq); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
 		return
 	}
 
@@ -45,87 +97,253 @@ func (ac *ApplicantionController) CreateApplication(c *gin.Context) {
 	scheme := models.Scheme{Id: applicationReq.SchemeID}
 	err := scheme.CheckSchemeExist(ctx, ac.DB)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to create application : " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	application := models.Application{}
 	if err := application.CreateApplication(ctx, ac.DB, applicationReq); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create application : " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": config.APPLICATION_SUBMIT_SUCCESS})
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.APPLICATION_SUBMIT_SUCCESS)})
 }
 
 // update applications
 func (ac *ApplicantionController) UpdateApplication(c *gin.Context) {
 	aid := c.Param("id")
 	if aid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update application : " + config.APPLICATION_ID_EMPTY})
+		respondError(c, invalidRequest(config.APPLICATION_ID_EMPTY))
 		return
 	}
 
 	ctx := c.Request.Context()
 	applicationId, err := uuid.Parse(aid)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update application : " + config.INVALID_APPLICATION_ID})
+		respondError(c, invalidRequest(config.INVALID_APPLICATION_ID))
+		return
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	applicationReq := models.ApplicationUpdateRequest{}
 	if err := c.ShouldBind(&applicationReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update application : " + err.Error()})
+		respondError(c, invalidBody(err))
 		return
 	}
 
-	if formValidate := validator.ValidateApplicationStatus(applicationReq.Status); !formValidate {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update application : " + config.REQUEST_FAILED})
+	// the actor is optional, falling back to the X-Actor header and then to the system
+	applicationReq.Actor = strings.TrimSpace(applicationReq.Actor)
+	if applicationReq.Actor == "" {
+		applicationReq.Actor = strings.TrimSpace(c.GetHeader(config.ActorHeader))
+	}
+	if applicationReq.Actor == "" {
+		applicationReq.Actor = config.ActorDefault
+	}
+
+	if fields := validator.ValidateApplicationUpdateForm(applicationReq); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
 		return
 	}
 
 	// check application exist
-	application := models.Application{Id: applicationId}
+	application := models.Application{Id: applicationId, Version: version}
 	err = application.CheckApplicationExist(ctx, ac.DB)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to update application : " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	if err := application.UpdateApplication(ctx, ac.DB, applicationReq); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update application : " + err.Error()})
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, application.Version)
+
+	// an approval over the scheme budget may have been waitlisted instead
+	if application.Status == config.StatusWaitlisted {
+		c.JSON(http.StatusOK, gin.H{"message": message(c, config.APPLICATION_WAITLISTED), "status": application.Status})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.APPLICATION_SUBMIT_SUCCESS), "status": application.Status})
+}
+
+// get allowed status transitions of an application
+func (ac *ApplicantionController) GetApplicationTransitions(c *gin.Context) {
+	aid := c.Param("id")
+	if aid == "" {
+		respondError(c, invalidRequest(config.APPLICATION_ID_EMPTY))
+		return
+	}
+
+	applicationId, err := uuid.Parse(aid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_APPLICATION_ID))
+		return
+	}
+
+	ctx := c.Request.Context()
+	application := models.Application{Id: applicationId}
+	if err := application.GetApplicationStatus(ctx, ac.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": application.Status, "transitions": models.AllowedTransitions(application.Status)})
+}
+
+// get status history of an application
+func (ac *ApplicantionController) GetApplicationHistory(c *gin.Context) {
+	aid := c.Param("id")
+	if aid == "" {
+		respondError(c, invalidRequest(config.APPLICATION_ID_EMPTY))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": config.APPLICATION_SUBMIT_SUCCESS})
+	applicationId, err := uuid.Parse(aid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_APPLICATION_ID))
+		return
+	}
+
+	ctx := c.Request.Context()
+	application := models.Application{Id: applicationId}
+	if err := application.CheckApplicationExist(ctx, ac.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	history, err := application.GetStatusHistory(ctx, ac.DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history})
 }
 
 // delete application
 func (ac *ApplicantionController) DeleteApplication(c *gin.Context) {
 	aid := c.Param("id")
 	if aid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete application : " + config.APPLICATION_ID_EMPTY})
+		respondError(c, invalidRequest(config.APPLICATION_ID_EMPTY))
 		return
 	}
 
 	applicationId, err := uuid.Parse(aid)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete application : " + config.INVALID_APPLICATION_ID})
+		respondError(c, invalidRequest(config.INVALID_APPLICATION_ID))
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	ctx := c.Request.Context()
 
-	application := models.Application{Id: applicationId}
+	application := models.Application{Id: applicationId, Version: version}
 	if err := application.CheckApplicationExist(ctx, ac.DB); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to delete application : " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	if err := application.DeleteApplication(ctx, ac.DB); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete application: " + err.Error()})
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.APPLICATION_DELETE_SUCCESS)})
+}
+
+// get the disbursement schedule of an application
+func (ac *ApplicantionController) GetDisbursements(c *gin.Context) {
+	application, ok := ac.pathApplication(c)
+	if !ok {
+		return
+	}
+
+	disbursements, err := application.GetDisbursements(c.Request.Context(), ac.DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"disbursements": disbursements})
+}
+
+// pause the scheduled disbursements of an application
+func (ac *ApplicantionController) PauseDisbursements(c *gin.Context) {
+	ac.updateDisbursements(c, models.PauseDisbursements, config.DISBURSEMENTS_PAUSE_SUCCESS)
+}
+
+// resume the paused disbursements of an application
+func (ac *ApplicantionController) ResumeDisbursements(c *gin.Context) {
+	ac.updateDisbursements(c, models.ResumeDisbursements, config.DISBURSEMENTS_RESUME_SUCCESS)
+}
+
+// cancel the scheduled and paused disbursements of an application
+func (ac *ApplicantionController) CancelDisbursements(c *gin.Context) {
+	ac.updateDisbursements(c, models.CancelDisbursements, config.DISBURSEMENTS_CANCEL_SUCCESS)
+}
+
+func (ac *ApplicantionController) updateDisbursements(c *gin.Context, action models.DisbursementAction, success string) {
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Application deleted successfully"})
+	application, ok := ac.pathApplication(c)
+	if !ok {
+		return
+	}
+	application.Version = version
+
+	ctx := c.Request.Context()
+	changed, err := application.UpdateDisbursements(ctx, ac.DB, action)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setETag(c, application.Version)
+
+	disbursements, err := application.GetDisbursements(ctx, ac.DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message(c, success), "changed": changed, "disbursements": disbursements})
+}
+
+// pathApplication reads the application of the route, writing the error response when it does not exist.
+func (ac *ApplicantionController) pathApplication(c *gin.Context) (models.Application, bool) {
+	aid := c.Param("id")
+	if aid == "" {
+		respondError(c, invalidRequest(config.APPLICATION_ID_EMPTY))
+		return models.Application{}, false
+	}
+
+	applicationId, err := uuid.Parse(aid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_APPLICATION_ID))
+		return models.Application{}, false
+	}
+
+	application := models.Application{Id: applicationId}
+	if err := application.CheckApplicationExist(c.Request.Context(), ac.DB); err != nil {
+		respondError(c, err)
+		return models.Application{}, false
+	}
+
+	return application, true
 }
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"oneCV/config"
+	"oneCV/models"
+	"oneCV/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type EducationLevelController struct {
+	DB *sql.DB
+}
+
+// get all education levels
+func (ec *EducationLevelController) GetAllEducationLevels(c *gin.Context) {
+	ctx := c.Request.Context()
+	level := models.EducationLevel{}
+	data, err := level.GetAllEducationLevels(ctx, ec.DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"education_levels": data})
+}
+
+// create education level
+func (ec *EducationLevelController) CreateEducationLevel(c *gin.Context) {
+	ctx := c.Request.Context()
+	level := models.EducationLevel{}
+	if err := c.ShouldBind(&level); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	if fields := validator.ValidateEducationLevelForm(level); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
+		return
+	}
+
+	if err := level.CreateEducationLevel(ctx, ec.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.EDUCATION_LEVEL_SUBMIT_SUCCESS)})
+}
+
+// update education level
+func (ec *EducationLevelController) UpdateEducationLevel(c *gin.Context) {
+	eid := c.Param("id")
+	if eid == "" {
+		respondError(c, invalidRequest(config.EDUCATION_LEVEL_ID_EMPTY))
+		return
+	}
+
+	ctx := c.Request.Context()
+	levelId, err := uuid.Parse(eid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_EDUCATION_LEVEL_ID))
+		return
+	}
+
+	level := models.EducationLevel{}
+	if err := c.ShouldBind(&level); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+	level.Id = levelId
+
+	if fields := validator.ValidateEducationLevelForm(level); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
+		return
+	}
+
+	// check does education level Id exist
+	if err := level.CheckEducationLevelExist(ctx, ec.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := level.UpdateEducationLevel(ctx, ec.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.EDUCATION_LEVEL_UPDATE_SUCCESS)})
+}
+
+// delete education level
+func (ec *EducationLevelController) DeleteEducationLevel(c *gin.Context) {
+	eid := c.Param("id")
+	if eid == "" {
+		respondError(c, invalidRequest(config.EDUCATION_LEVEL_ID_EMPTY))
+		return
+	}
+
+	levelId, err := uuid.Parse(eid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_EDUCATION_LEVEL_ID))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	level := models.EducationLevel{Id: levelId}
+	if err := level.CheckEducationLevelExist(ctx, ec.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := level.DeleteEducationLevel(ctx, ec.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.EDUCATION_LEVEL_DELETE_SUCCESS)})
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"oneCV/config"
+	"oneCV/models"
+	"oneCV/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EligibilityController struct {
+	DB *sql.DB
+}
+
+// simulate eligibility for an applicant profile that is not saved
+func (ec *EligibilityController) SimulateEligibility(c *gin.Context) {
+	ctx := c.Request.Context()
+	applicant := models.Applicant{}
+	if err := c.ShouldBind(&applicant); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	if fields := validator.ValidateApplicantForm(applicant); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
+		return
+	}
+
+	evaluator, err := evaluatorAsOf(c, ec.DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	scheme := models.Scheme{}
+	schemes, err := scheme.SimulateEligibility(ctx, ec.DB, evaluator, applicant)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if len(schemes) == 0 {
+		c.JSON(http.StatusOK, gin.H{"schemes": schemes, "message": message(c, config.NO_ELIGIBLE_SCHEME)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schemes": schemes})
+}
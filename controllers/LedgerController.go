@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"oneCV/config"
+	"oneCV/models"
+	"oneCV/validator"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type LedgerController struct {
+	DB *sql.DB
+}
+
+// post a payout of a disbursement to the ledger
+func (lc *LedgerController) PostPayout(c *gin.Context) {
+	key, ok := idempotencyKey(c)
+	if !ok {
+		return
+	}
+
+	payout := models.PayoutRequest{}
+	if err := c.ShouldBindJSON(&payout); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	if fields := validator.ValidatePayoutForm(payout); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
+		return
+	}
+
+	entry := models.LedgerEntry{}
+	replayed, err := entry.PostPayout(c.Request.Context(), lc.DB, key, payout)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	respondLedgerEntry(c, entry, replayed, config.PAYOUT_POST_SUCCESS)
+}
+
+// get a ledger entry by ID
+func (lc *LedgerController) GetLedgerEntry(c *gin.Context) {
+	entry, ok := ledgerEntryId(c)
+	if !ok {
+		return
+	}
+
+	if err := entry.GetLedgerEntry(c.Request.Context(), lc.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entry": entry})
+}
+
+// record whether a scheduled payout was paid or failed
+func (lc *LedgerController) SettlePayout(c *gin.Context) {
+	entry, ok := ledgerEntryId(c)
+	if !ok {
+		return
+	}
+
+	settle := models.SettleRequest{}
+	if err := c.ShouldBindJSON(&settle); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	if fields := validator.ValidateSettleForm(settle); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
+		return
+	}
+
+	if err := entry.SettlePayout(c.Request.Context(), lc.DB, settle); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.PAYOUT_SETTLE_SUCCESS), "entry": entry})
+}
+
+// reverse a paid payout
+func (lc *LedgerController) ReversePayout(c *gin.Context) {
+	entry, ok := ledgerEntryId(c)
+	if !ok {
+		return
+	}
+
+	key, ok := idempotencyKey(c)
+	if !ok {
+		return
+	}
+
+	reversal := models.ReversalRequest{}
+	if err := c.ShouldBindJSON(&reversal); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	if fields := validator.ValidateReversalForm(reversal); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
+		return
+	}
+
+	replayed, err := entry.ReversePayout(c.Request.Context(), lc.DB, key, reversal)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	respondLedgerEntry(c, entry, replayed, config.PAYOUT_REVERSE_SUCCESS)
+}
+
+// get the ledger entries of an application
+func (lc *LedgerController) GetApplicationLedger(c *gin.Context) {
+	aid := c.Param("id")
+	if aid == "" {
+		respondError(c, invalidRequest(config.APPLICATION_ID_EMPTY))
+		return
+	}
+
+	applicationId, err := uuid.Parse(aid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_APPLICATION_ID))
+		return
+	}
+
+	ctx := c.Request.Context()
+	application := models.Application{Id: applicationId}
+	if err := application.CheckApplicationExist(ctx, lc.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	entries, err := application.GetLedger(ctx, lc.DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// export the ledger entries dated within a period as CSV for reconciliation
+func (lc *LedgerController) ExportReconciliation(c *gin.Context) {
+	from, err := queryDate(c, "from")
+	if err != nil || from == "" {
+		respondError(c, invalidRequest(config.INVALID_RECONCILIATION_PERIOD))
+		return
+	}
+	to, err := queryDate(c, "to")
+	if err != nil || to == "" || to < from {
+		respondError(c, invalidRequest(config.INVALID_RECONCILIATION_PERIOD))
+		return
+	}
+
+	entry := models.LedgerEntry{}
+	rows, err := entry.Reconciliation(c.Request.Context(), lc.DB, from, to)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// the file is built before anything is sent so a failed write is an error response, not a truncated file
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	if err := writer.Write(models.ReconciliationHeader); err != nil {
+		log.Println("Error writing reconciliation header:", err)
+		respondError(c, err)
+		return
+	}
+	for _, row := range rows {
+		if err := writer.Write(row.Record()); err != nil {
+			log.Println("Error writing reconciliation row:", err)
+			respondError(c, err)
+			return
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Println("Error writing reconciliation export:", err)
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="reconciliation_%s_%s.csv"`, from, to))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buffer.Bytes())
+}
+
+// respondLedgerEntry writes a posted entry, marking the response of a replayed request.
+func respondLedgerEntry(c *gin.Context, entry models.LedgerEntry, replayed bool, success string) {
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message(c, success), "entry": entry})
+}
+
+// idempotencyKey reads the Idempotency-Key header that ledger postings must send.
+func idempotencyKey(c *gin.Context) (string, bool) {
+	key := strings.TrimSpace(c.GetHeader("Idempotency-Key"))
+	if key == "" || len(key) > 255 {
+		respondError(c, invalidRequest(config.IDEMPOTENCY_KEY_REQUIRED))
+		return "", false
+	}
+	return key, true
+}
+
+func ledgerEntryId(c *gin.Context) (models.LedgerEntry, bool) {
+	eid := c.Param("id")
+	if eid == "" {
+		respondError(c, invalidRequest(config.LEDGER_ENTRY_ID_EMPTY))
+		return models.LedgerEntry{}, false
+	}
+
+	entryId, err := uuid.Parse(eid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_LEDGER_ENTRY_ID))
+		return models.LedgerEntry{}, false
+	}
+
+	return models.LedgerEntry{Id: entryId}, true
+}
@@ -4,8 +4,12 @@ import (
 	"database/sql"
 	"net/http"
 	"oneCV/config"
+	"oneCV/eligibility"
 	"oneCV/models"
 	"oneCV/validator"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -17,14 +21,44 @@ type SchemeController struct {
 
 // get all schemes
 func (sc *SchemeController) GetAllSchemes(c *gin.Context) {
+	query, err := listQuery(c, "desc")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	state := strings.ToLower(c.Query("state"))
+	if state != "" && !validator.Validator(state, []string{config.SchemeUpcoming, config.SchemeActive, config.SchemeExpired}) {
+		respondError(c, models.InvalidListQueryError(models.ReasonInvalidFilter, "state", state))
+		return
+	}
+
+	// states are as of today unless another date is asked for
+	date, err := queryDate(c, "date")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+
+	filter := models.SchemeFilter{
+		Name:              c.Query("name"),
+		ApplicationPolicy: c.Query("application_policy"),
+		State:             state,
+		Date:              date,
+	}
+	filter.Apply(&query)
+
 	ctx := c.Request.Context()
 	scheme := models.Scheme{}
-	data, err := scheme.GetAllSchemes(ctx, sc.DB)
+	data, page, err := scheme.GetAllSchemes(ctx, sc.DB, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get schemes : " + err.Error()})
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"schemes": data})
+	c.JSON(http.StatusOK, gin.H{"schemes": data, "limit": page.Limit, "total": page.Total, "next_cursor": page.NextCursor})
 }
 
 // create scheme
@@ -33,22 +67,28 @@ func (sc *SchemeController) CreateScheme(c *gin.Context) {
 	var schemeReq models.SchemeRequest
 
 	if err := c.ShouldBindJSON(&schemeReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create scheme : " + err.Error()})
+		respondError(c, invalidBody(err))
 		return
 	}
 
-	if formValidate := validator.ValidateSchemeForm(schemeReq); !formValidate {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create scheme : " + config.REQUEST_FAILED})
+	if fields := validator.ValidateSchemeForm(schemeReq); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
+		return
+	}
+
+	educationLevel := models.EducationLevel{}
+	if err := educationLevel.CheckEducationLevelsExist(ctx, sc.DB, schemeReq.SchoolLevels()); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	scheme := models.Scheme{}
 	if err := scheme.CreateScheme(ctx, sc.DB, schemeReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create scheme : " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": config.SCHEME_SUBMIT_SUCCESS})
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.SCHEME_SUBMIT_SUCCESS)})
 }
 
 // get eligible schemes
@@ -57,104 +97,557 @@ func (sc *SchemeController) GetEligibleSchemes(c *gin.Context) {
 	aid := c.DefaultQuery("applicant", "")
 
 	if aid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get eligible scheme : " + config.APPLICANT_ID_EMPTY})
+		respondError(c, invalidRequest(config.APPLICANT_ID_EMPTY))
 		return
 	}
 
 	applicantId, err := uuid.Parse(aid)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get eligible scheme : " + config.INVALID_APPLICANT_ID})
+		respondError(c, invalidRequest(config.INVALID_APPLICANT_ID))
 		return
 	}
 
 	applicant := models.Applicant{Id: applicantId}
 	if err := applicant.GetApplicantById(ctx, sc.DB); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get eligible scheme : " + config.APPLICANT_NOT_FOUND})
+		respondError(c, err)
+		return
+	}
+
+	evaluator, err := sc.evaluator(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	scheme := models.Scheme{}
-	schemes, err := scheme.GetEligibleSchemes(ctx, sc.DB, applicant)
+	schemes, err := scheme.GetEligibleSchemes(ctx, sc.DB, evaluator, applicant)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get eligible scheme : " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	if len(schemes) == 0 {
-		c.JSON(http.StatusOK, gin.H{"scheme": schemes, "message": config.NO_ELIGIBLE_SCHEME})
+		c.JSON(http.StatusOK, gin.H{"scheme": schemes, "message": message(c, config.NO_ELIGIBLE_SCHEME)})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"scheme": schemes})
 }
 
+// explain scheme eligibility for an applicant
+func (sc *SchemeController) GetSchemeEligibility(c *gin.Context) {
+	sid := c.Param("id")
+	if sid == "" {
+		respondError(c, invalidRequest(config.SCHEME_ID_EMPTY))
+		return
+	}
+
+	schemeId, err := uuid.Parse(sid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_SCHEME_ID))
+		return
+	}
+
+	aid := c.DefaultQuery("applicant", "")
+	if aid == "" {
+		respondError(c, invalidRequest(config.APPLICANT_ID_EMPTY))
+		return
+	}
+
+	applicantId, err := uuid.Parse(aid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_APPLICANT_ID))
+		return
+	}
+
+	ctx := c.Request.Context()
+	applicant := models.Applicant{Id: applicantId}
+	if err := applicant.GetApplicantById(ctx, sc.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	scheme := models.Scheme{Id: schemeId}
+	if err := scheme.GetSchemeById(ctx, sc.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	evaluator, err := sc.evaluator(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	report, err := scheme.ExplainEligibility(evaluator, applicant)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"eligibility": report})
+}
+
+// get applicants eligible for a scheme
+func (sc *SchemeController) GetEligibleApplicants(c *gin.Context) {
+	sid := c.Param("id")
+	if sid == "" {
+		respondError(c, invalidRequest(config.SCHEME_ID_EMPTY))
+		return
+	}
+
+	schemeId, err := uuid.Parse(sid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_SCHEME_ID))
+		return
+	}
+
+	q, err := listQuery(c, "asc")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	scheme := models.Scheme{Id: schemeId}
+	if err := scheme.GetSchemeById(ctx, sc.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	evaluator, err := sc.evaluator(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	applicants, page, err := scheme.GetEligibleApplicants(ctx, sc.DB, evaluator, q)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"applicants": applicants, "limit": page.Limit, "next_cursor": page.NextCursor})
+}
+
+// preview the impact of new scheme criteria without saving them
+func (sc *SchemeController) PreviewSchemeImpact(c *gin.Context) {
+	sid := c.Param("id")
+	if sid == "" {
+		respondError(c, invalidRequest(config.SCHEME_ID_EMPTY))
+		return
+	}
+
+	schemeId, err := uuid.Parse(sid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_SCHEME_ID))
+		return
+	}
+
+	var schemeReq models.SchemeRequest
+	if err := c.ShouldBindJSON(&schemeReq); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	if fields := validator.ValidateSchemeForm(schemeReq); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
+		return
+	}
+
+	ctx := c.Request.Context()
+	scheme := models.Scheme{Id: schemeId}
+	if err := scheme.GetSchemeById(ctx, sc.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	educationLevel := models.EducationLevel{}
+	if err := educationLevel.CheckEducationLevelsExist(ctx, sc.DB, schemeReq.SchoolLevels()); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	evaluator, err := sc.evaluator(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	impact, err := scheme.PreviewImpact(ctx, sc.DB, evaluator, schemeReq)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"impact": impact})
+}
+
+// get scheme by ID
+func (sc *SchemeController) GetSchemeByID(c *gin.Context) {
+	aid := c.Param("id")
+	if aid == "" {
+		respondError(c, invalidRequest(config.SCHEME_ID_EMPTY))
+		return
+	}
+
+	schemeId, err := uuid.Parse(aid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_SCHEME_ID))
+		return
+	}
+
+	scheme := models.Scheme{Id: schemeId}
+	if err := scheme.GetSchemeById(c.Request.Context(), sc.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, scheme.Version)
+	c.JSON(http.StatusOK, gin.H{"scheme": scheme})
+}
+
 // update scheme
 func (sc *SchemeController) UpdateScheme(c *gin.Context) {
 	aid := c.Param("id")
 	if aid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update scheme : " + config.SCHEME_ID_EMPTY})
+		respondError(c, invalidRequest(config.SCHEME_ID_EMPTY))
 		return
 	}
 
 	ctx := c.Request.Context()
 	schemeId, err := uuid.Parse(aid)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update scheme : " + config.INVALID_SCHEME_ID})
+		respondError(c, invalidRequest(config.INVALID_SCHEME_ID))
+		return
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 	var schemeReq models.SchemeRequest
-	scheme := models.Scheme{Id: schemeId}
+	scheme := models.Scheme{Id: schemeId, Version: version}
 	if err := c.ShouldBind(&schemeReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update scheme : " + err.Error()})
+		respondError(c, invalidBody(err))
 		return
 	}
 
-	if formValidate := validator.ValidateSchemeForm(schemeReq); !formValidate {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update scheme : " + config.REQUEST_FAILED})
+	if fields := validator.ValidateSchemeForm(schemeReq); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
 		return
 	}
 
 	// check does scheme Id exist
 	err = scheme.CheckSchemeExist(ctx, sc.DB)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to update scheme : " + err.Error()})
+		respondError(c, err)
+		return
+	}
+
+	educationLevel := models.EducationLevel{}
+	if err := educationLevel.CheckEducationLevelsExist(ctx, sc.DB, schemeReq.SchoolLevels()); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	if err := scheme.UpdateScheme(ctx, sc.DB, schemeReq); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheme : " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": config.SCHEME_UPDATE_SUCCESS})
+	setETag(c, scheme.Version)
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.SCHEME_UPDATE_SUCCESS)})
+}
+
+// patch scheme with a JSON merge patch, criteria and benefits are kept by their id
+func (sc *SchemeController) PatchScheme(c *gin.Context) {
+	aid := c.Param("id")
+	if aid == "" {
+		respondError(c, invalidRequest(config.SCHEME_ID_EMPTY))
+		return
+	}
+
+	ctx := c.Request.Context()
+	schemeId, err := uuid.Parse(aid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_SCHEME_ID))
+		return
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	scheme := models.Scheme{Id: schemeId}
+	if err := scheme.GetSchemeById(ctx, sc.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+	// the patch must be made against the version the client read
+	if err := models.CheckVersion(schemeId, scheme.Version, version); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var schemeReq models.SchemeRequest
+	if err := mergePatch(c, scheme.Request(), &schemeReq); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if fields := validator.ValidateSchemeForm(schemeReq); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
+		return
+	}
+
+	educationLevel := models.EducationLevel{}
+	if err := educationLevel.CheckEducationLevelsExist(ctx, sc.DB, schemeReq.SchoolLevels()); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	scheme.Version = version
+	if err := scheme.UpdateScheme(ctx, sc.DB, schemeReq); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := scheme.GetSchemeById(ctx, sc.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, scheme.Version)
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.SCHEME_UPDATE_SUCCESS), "scheme": scheme})
 }
 
 // delete scheme
 func (sc *SchemeController) DeleteScheme(c *gin.Context) {
 	aid := c.Param("id")
 	if aid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete scheme : " + config.SCHEME_ID_EMPTY})
+		respondError(c, invalidRequest(config.SCHEME_ID_EMPTY))
 		return
 	}
 
 	schemeId, err := uuid.Parse(aid)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete scheme : " + config.INVALID_SCHEME_ID})
+		respondError(c, invalidRequest(config.INVALID_SCHEME_ID))
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	ctx := c.Request.Context()
 
-	scheme := models.Scheme{Id: schemeId}
+	scheme := models.Scheme{Id: schemeId, Version: version}
 	if err := scheme.CheckSchemeExist(ctx, sc.DB); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to delete scheme : " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	if err := scheme.DeleteScheme(ctx, sc.DB); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete scheme: " + err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Scheme deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.SCHEME_DELETE_SUCCESS)})
+}
+
+// get the budget and recipients committed by the approved applications of a scheme
+func (sc *SchemeController) GetSchemeFunding(c *gin.Context) {
+	scheme, ok := sc.pathScheme(c)
+	if !ok {
+		return
+	}
+
+	usage, err := scheme.GetFundingUsage(c.Request.Context(), sc.DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"funding": usage})
+}
+
+// get the versions of a scheme, the draft first then the newest published
+func (sc *SchemeController) GetSchemeVersions(c *gin.Context) {
+	scheme, ok := sc.pathScheme(c)
+	if !ok {
+		return
+	}
+
+	versions, err := scheme.GetVersions(c.Request.Context(), sc.DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// get a published or retired version of a scheme by its number
+func (sc *SchemeController) GetSchemeVersion(c *gin.Context) {
+	scheme, ok := sc.pathScheme(c)
+	if !ok {
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil || number <= 0 {
+		respondError(c, invalidRequest(config.INVALID_SCHEME_VERSION))
+		return
+	}
+
+	version, err := scheme.GetVersion(c.Request.Context(), sc.DB, number)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"version": version})
+}
+
+// create or replace the draft of a scheme, the published version is unchanged until the draft is published
+func (sc *SchemeController) SaveSchemeDraft(c *gin.Context) {
+	scheme, ok := sc.pathScheme(c)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var schemeReq models.SchemeRequest
+	if err := c.ShouldBind(&schemeReq); err != nil {
+		respondError(c, invalidBody(err))
+		return
+	}
+
+	if fields := validator.ValidateSchemeForm(schemeReq); len(fields) > 0 {
+		respondError(c, invalidForm(fields))
+		return
+	}
+
+	ctx := c.Request.Context()
+	educationLevel := models.EducationLevel{}
+	if err := educationLevel.CheckEducationLevelsExist(ctx, sc.DB, schemeReq.SchoolLevels()); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	scheme.Version = version
+	if err := scheme.SaveDraft(ctx, sc.DB, schemeReq); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	draft, err := scheme.GetDraft(ctx, sc.DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, scheme.Version)
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.SCHEME_DRAFT_SAVE_SUCCESS), "version": draft})
+}
+
+// discard the draft of a scheme
+func (sc *SchemeController) DeleteSchemeDraft(c *gin.Context) {
+	scheme, ok := sc.pathScheme(c)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	scheme.Version = version
+	if err := scheme.DeleteDraft(c.Request.Context(), sc.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, scheme.Version)
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.SCHEME_DRAFT_DELETE_SUCCESS)})
+}
+
+// publish the draft of a scheme as its new version
+func (sc *SchemeController) PublishSchemeDraft(c *gin.Context) {
+	scheme, ok := sc.pathScheme(c)
+	if !ok {
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	draft, err := scheme.GetDraft(ctx, sc.DB)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// education levels may have been removed since the draft was saved
+	educationLevel := models.EducationLevel{}
+	if err := educationLevel.CheckEducationLevelsExist(ctx, sc.DB, draft.Definition.SchoolLevels()); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	scheme.Version = version
+	if err := scheme.PublishDraft(ctx, sc.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := scheme.GetSchemeById(ctx, sc.DB); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, scheme.Version)
+	c.JSON(http.StatusOK, gin.H{"message": message(c, config.SCHEME_PUBLISH_SUCCESS), "scheme": scheme})
+}
+
+// pathScheme returns the scheme of the route id, writing the error response when it does not exist.
+func (sc *SchemeController) pathScheme(c *gin.Context) (models.Scheme, bool) {
+	aid := c.Param("id")
+	if aid == "" {
+		respondError(c, invalidRequest(config.SCHEME_ID_EMPTY))
+		return models.Scheme{}, false
+	}
+
+	schemeId, err := uuid.Parse(aid)
+	if err != nil {
+		respondError(c, invalidRequest(config.INVALID_SCHEME_ID))
+		return models.Scheme{}, false
+	}
+
+	scheme := models.Scheme{Id: schemeId}
+	if err := scheme.CheckSchemeExist(c.Request.Context(), sc.DB); err != nil {
+		respondError(c, err)
+		return models.Scheme{}, false
+	}
+
+	return scheme, true
+}
+
+// build the eligibility evaluator, as of the optional date query (YYYY-MM-DD)
+func (sc *SchemeController) evaluator(c *gin.Context) (eligibility.Evaluator, error) {
+	return evaluatorAsOf(c, sc.DB)
 }
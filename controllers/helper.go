@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"oneCV/config"
+	"oneCV/eligibility"
+	"oneCV/models"
+	"oneCV/utils"
+	"oneCV/validator"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// evaluatorAsOf builds the eligibility evaluator as of the optional date query (YYYY-MM-DD), today when it is not sent.
+func evaluatorAsOf(c *gin.Context, db *sql.DB) (eligibility.Evaluator, error) {
+	evaluator, err := models.NewEvaluator(c.Request.Context(), db)
+	if err != nil {
+		return evaluator, err
+	}
+
+	if date := c.Query("date"); date != "" {
+		if !validator.ValidateDate(date) {
+			return evaluator, invalidRequest(config.INVALID_EVALUATION_DATE)
+		}
+		evaluationDate, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return evaluator, invalidRequest(config.INVALID_EVALUATION_DATE)
+		}
+		evaluator = evaluator.WithDate(evaluationDate)
+	}
+
+	return evaluator, nil
+}
+
+// listQuery reads the limit, cursor, sort and order query values shared by the list endpoints.
+func listQuery(c *gin.Context, defaultOrder string) (models.ListQuery, error) {
+	limit, err := utils.ParseLimit(c.DefaultQuery("limit", ""))
+	if err != nil {
+		return models.ListQuery{}, models.InvalidListQueryError(models.ReasonInvalidLimit, "limit", c.Query("limit"))
+	}
+
+	order := strings.ToLower(c.DefaultQuery("order", defaultOrder))
+	if order != "asc" && order != "desc" {
+		return models.ListQuery{}, models.InvalidListQueryError(models.ReasonInvalidOrder, "order", order)
+	}
+
+	return models.ListQuery{
+		Limit:  limit,
+		Cursor: c.Query("cursor"),
+		Sort:   strings.ToLower(c.Query("sort")),
+		Desc:   order == "desc",
+	}, nil
+}
+
+// queryId reads an optional uuid query value.
+func queryId(c *gin.Context, key string) (*uuid.UUID, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, models.InvalidListQueryError(models.ReasonInvalidFilter, key, value)
+	}
+	return &id, nil
+}
+
+// queryDate reads an optional YYYY-MM-DD query value.
+func queryDate(c *gin.Context, key string) (string, error) {
+	value := c.Query(key)
+	if value == "" {
+		return "", nil
+	}
+
+	if _, err := time.Parse("2006-01-02", value); err != nil {
+		return "", models.InvalidListQueryError(models.ReasonInvalidFilter, key, value)
+	}
+	return value, nil
+}
+
+// mergePatch applies the JSON merge patch in the request body to current and decodes the result into patched.
+func mergePatch(c *gin.Context, current interface{}, patched interface{}) error {
+	patch, err := c.GetRawData()
+	if err != nil {
+		return invalidBody(err)
+	}
+
+	target, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	document, err := utils.MergePatch(target, patch)
+	if err != nil {
+		return invalidBody(err)
+	}
+
+	if err := json.Unmarshal(document, patched); err != nil {
+		return invalidBody(err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"oneCV/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const localeKey = "locale"
+
+// Localize picks the response language from the Accept-Language header.
+func Localize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := config.MatchLocale(c.GetHeader("Accept-Language"))
+		c.Set(localeKey, locale)
+		c.Header("Content-Language", locale)
+		c.Next()
+	}
+}
+
+func locale(c *gin.Context) string {
+	if locale := c.GetString(localeKey); locale != "" {
+		return locale
+	}
+	return config.DefaultLocale
+}
+
+// message returns the text of a message id in the language of the request.
+func message(c *gin.Context, id string) string {
+	return config.Message(locale(c), id)
+}
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"oneCV/config"
+	"oneCV/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setETag sends the version of the record as its entity tag, e.g. "3".
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// ifMatch reads the record version a write is made against from the If-Match header, 0 for "*"
+// which matches any version. Writes to versioned records must send the header.
+func ifMatch(c *gin.Context) (int, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return 0, models.PreconditionRequiredError(models.CodePreconditionRequired, config.PRECONDITION_REQUIRED)
+	}
+	if header == "*" {
+		return 0, nil
+	}
+
+	// only a single strong entity tag can name a version
+	tag, err := strconv.Unquote(header)
+	if err != nil || !strings.HasPrefix(header, `"`) {
+		return 0, invalidRequest(config.INVALID_IF_MATCH)
+	}
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return 0, invalidRequest(config.INVALID_IF_MATCH)
+	}
+	return version, nil
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"oneCV/config"
+	"oneCV/models"
+	"oneCV/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HTTP status reported for each kind of error
+var errorStatus = map[models.ErrorKind]int{
+	models.KindNotFound:             http.StatusNotFound,
+	models.KindConflict:             http.StatusConflict,
+	models.KindValidation:           http.StatusBadRequest,
+	models.KindPreconditionFailed:   http.StatusPreconditionFailed,
+	models.KindPreconditionRequired: http.StatusPreconditionRequired,
+	models.KindInternal:             http.StatusInternalServerError,
+}
+
+// respondError writes err in the error envelope with the HTTP status of its kind. Errors that are not
+// a *models.Error are logged and reported as internal errors without their message.
+func respondError(c *gin.Context, err error) {
+	appErr := models.AsError(err)
+	if appErr.Kind == models.KindInternal {
+		log.Printf("Internal error on %s %s: %v", c.Request.Method, c.FullPath(), err)
+	}
+
+	// translate a copy so a shared error is left untouched
+	body := *appErr
+	body.Message = message(c, appErr.Message)
+	body.Fields = make([]models.FieldError, len(appErr.Fields))
+	for i, field := range appErr.Fields {
+		field.Message, _ = config.LookupMessage(locale(c), "rule_"+field.Rule)
+		body.Fields[i] = field
+	}
+
+	c.JSON(errorStatus[appErr.Kind], gin.H{"error": body})
+}
+
+// invalidRequest is the error for a malformed path or query value.
+func invalidRequest(message string) *models.Error {
+	return models.ValidationError(models.CodeInvalidRequest, message)
+}
+
+// invalidBody is the error for a request body that cannot be bound, listing the rejected fields when known.
+func invalidBody(err error) *models.Error {
+	appErr := models.ValidationError(models.CodeInvalidRequest, config.INVALID_REQUEST_BODY)
+	if fields := validator.BindingErrors(err); len(fields) > 0 {
+		appErr = invalidForm(fields)
+	}
+	appErr.Err = err
+	return appErr
+}
+
+// invalidForm is the error for a request body that failed validation.
+func invalidForm(fields []models.FieldError) *models.Error {
+	return models.ValidationError(models.CodeValidationFailed, config.REQUEST_FAILED, fields...)
+}
@@ -0,0 +1,69 @@
+package eligibility
+
+import (
+	"fmt"
+	"oneCV/utils"
+	"time"
+)
+
+// How an age is worked out for a criterion.
+const (
+	// completed years on the evaluation date
+	AgeAsOfDate = "as_of_date"
+	// completed years on 1 Jan of the evaluation year
+	AgeAsOfYearStart = "as_of_year_start"
+	// evaluation year minus birth year, i.e. the age turned during the year (school cohort)
+	AgeByBirthYear = "birth_year"
+)
+
+func IsAgeBasis(basis string) bool {
+	return basis == AgeAsOfDate || basis == AgeAsOfYearStart || basis == AgeByBirthYear
+}
+
+// WithDate returns a copy of the evaluator that evaluates rules as of the given date.
+func (e Evaluator) WithDate(date time.Time) Evaluator {
+	e.date = date
+	return e
+}
+
+// Date is the evaluation date, defaulting to today.
+func (e Evaluator) Date() time.Time {
+	if e.date.IsZero() {
+		return time.Now()
+	}
+	return e.date
+}
+
+// Age returns the age of a person born on dateOfBirth using the given basis.
+func (e Evaluator) Age(dateOfBirth string, basis string) (int, error) {
+	date := e.Date()
+
+	switch basis {
+	case "", AgeAsOfDate:
+		return utils.CalculateAgeOn(dateOfBirth, date)
+	case AgeAsOfYearStart:
+		return utils.CalculateAgeOn(dateOfBirth, time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, date.Location()))
+	case AgeByBirthYear:
+		birthDate, err := time.Parse("2006-01-02", dateOfBirth)
+		if err != nil {
+			return 0, fmt.Errorf("invalid date format: %v", err)
+		}
+		return date.Year() - birthDate.Year(), nil
+	}
+
+	return 0, fmt.Errorf("unknown age basis: %s", basis)
+}
+
+// ageBasis reads the optional age_basis entry of an object criteria value.
+func ageBasis(condition map[string]interface{}) (string, error) {
+	value, ok := condition["age_basis"]
+	if !ok {
+		return "", nil
+	}
+
+	basis, ok := value.(string)
+	if !ok || !IsAgeBasis(basis) {
+		return "", fmt.Errorf("invalid age_basis: %v", value)
+	}
+	return basis, nil
+}
@@ -0,0 +1,50 @@
+package eligibility
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluatorAge(t *testing.T) {
+	// evaluated on 2024-06-14 for someone born on 2010-06-15, the day before their 14th birthday
+	on := time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		basis       string
+		dateOfBirth string
+		want        int
+	}{
+		{"", "2010-06-15", 13},
+		{AgeAsOfDate, "2010-06-15", 13},
+		{AgeAsOfDate, "2010-06-14", 14},
+		{AgeAsOfYearStart, "2010-06-15", 13},
+		{AgeAsOfYearStart, "2010-01-01", 14},
+		{AgeAsOfYearStart, "2010-01-02", 13},
+		{AgeByBirthYear, "2010-06-15", 14},
+		{AgeByBirthYear, "2010-12-31", 14},
+	}
+
+	e := NewEvaluator(nil).WithDate(on)
+	for _, tt := range tests {
+		got, err := e.Age(tt.dateOfBirth, tt.basis)
+		if err != nil {
+			t.Fatalf("Age(%q, %q) returned error: %v", tt.dateOfBirth, tt.basis, err)
+		}
+		if got != tt.want {
+			t.Errorf("Age(%q, %q) = %d, want %d", tt.dateOfBirth, tt.basis, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluatorAgeErrors(t *testing.T) {
+	e := NewEvaluator(nil).WithDate(time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC))
+
+	if _, err := e.Age("2010-06-15", "lunar"); err == nil {
+		t.Error("Age with an unknown basis returned no error")
+	}
+	for _, basis := range []string{AgeAsOfDate, AgeAsOfYearStart, AgeByBirthYear} {
+		if _, err := e.Age("15/06/2010", basis); err == nil {
+			t.Errorf("Age with a malformed date of birth and basis %q returned no error", basis)
+		}
+	}
+}
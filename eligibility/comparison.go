@@ -0,0 +1,110 @@
+package eligibility
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Comparison is a single numeric check such as ">= 60".
+type Comparison struct {
+	Operator string
+	Operand  float64
+}
+
+// longer operators first so ">=" is not read as ">"
+var operators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// ParseComparisons reads a numeric criteria value. It accepts a number (equality),
+// a comparison string such as "< 2000", or a list of comparisons that must all
+// hold, e.g. [">= 18", "< 60"] for a range.
+func ParseComparisons(value interface{}) ([]Comparison, error) {
+	switch v := value.(type) {
+	case float64:
+		return []Comparison{{Operator: "==", Operand: v}}, nil
+	case string:
+		comparison, err := parseComparison(v)
+		if err != nil {
+			return nil, err
+		}
+		return []Comparison{comparison}, nil
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("numeric criteria cannot be an empty list")
+		}
+
+		comparisons := []Comparison{}
+		for _, item := range v {
+			parsed, err := ParseComparisons(item)
+			if err != nil {
+				return nil, err
+			}
+			comparisons = append(comparisons, parsed...)
+		}
+		return comparisons, nil
+	}
+
+	return nil, fmt.Errorf("invalid numeric criteria: %v", value)
+}
+
+// ParseNumericCondition reads a numeric criteria value, which can also be written as
+// {"compare": ">= 60", "age_basis": "as_of_year_start"} to choose how an age is worked out.
+func ParseNumericCondition(value interface{}) ([]Comparison, string, error) {
+	condition, ok := value.(map[string]interface{})
+	if !ok {
+		comparisons, err := ParseComparisons(value)
+		return comparisons, "", err
+	}
+
+	basis, err := ageBasis(condition)
+	if err != nil {
+		return nil, "", err
+	}
+
+	compare, ok := condition["compare"]
+	if !ok {
+		return nil, "", fmt.Errorf("numeric criteria requires compare: %v", value)
+	}
+
+	comparisons, err := ParseComparisons(compare)
+	return comparisons, basis, err
+}
+
+func parseComparison(value string) (Comparison, error) {
+	value = strings.TrimSpace(value)
+	operator := "=="
+	for _, op := range operators {
+		if strings.HasPrefix(value, op) {
+			operator = op
+			value = strings.TrimSpace(strings.TrimPrefix(value, op))
+			break
+		}
+	}
+
+	operand, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("invalid numeric criteria: %q", value)
+	}
+
+	return Comparison{Operator: operator, Operand: operand}, nil
+}
+
+func (c Comparison) Match(value float64) bool {
+	switch c.Operator {
+	case ">=":
+		return value >= c.Operand
+	case "<=":
+		return value <= c.Operand
+	case ">":
+		return value > c.Operand
+	case "<":
+		return value < c.Operand
+	case "!=":
+		return value != c.Operand
+	}
+	return value == c.Operand
+}
+
+func (c Comparison) String() string {
+	return c.Operator + " " + strconv.FormatFloat(c.Operand, 'f', -1, 64)
+}
@@ -0,0 +1,99 @@
+package eligibility
+
+import "testing"
+
+func TestComparisonMatch(t *testing.T) {
+	tests := []struct {
+		value string
+		input float64
+		want  bool
+	}{
+		{"== 60", 60, true},
+		{"== 60", 61, false},
+		{"60", 60, true},
+		{"!= 60", 59, true},
+		{"!= 60", 60, false},
+		{"> 60", 61, true},
+		{"> 60", 60, false},
+		{">= 60", 60, true},
+		{">= 60", 59, false},
+		{"< 2000", 1999.99, true},
+		{"< 2000", 2000, false},
+		{"<= 2000", 2000, true},
+		{"<= 2000", 2000.01, false},
+		{" >=60 ", 60, true},
+	}
+
+	for _, tt := range tests {
+		comparisons, err := ParseComparisons(tt.value)
+		if err != nil {
+			t.Fatalf("ParseComparisons(%q) returned error: %v", tt.value, err)
+		}
+		if len(comparisons) != 1 {
+			t.Fatalf("ParseComparisons(%q) = %v, want one comparison", tt.value, comparisons)
+		}
+		if got := comparisons[0].Match(tt.input); got != tt.want {
+			t.Errorf("%q.Match(%v) = %v, want %v", tt.value, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseComparisons(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    []Comparison
+		wantErr bool
+	}{
+		{"number", float64(3), []Comparison{{"==", 3}}, false},
+		{"comparison", ">= 18", []Comparison{{">=", 18}}, false},
+		{"range", []interface{}{">= 18", "< 60"}, []Comparison{{">=", 18}, {"<", 60}}, false},
+		{"empty list", []interface{}{}, nil, true},
+		{"not a number", ">= many", nil, true},
+		{"unsupported type", true, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseComparisons(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseComparisons(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseComparisons(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ParseComparisons(%v)[%d] = %v, want %v", tt.value, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseNumericCondition(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     interface{}
+		wantBasis string
+		wantErr   bool
+	}{
+		{"plain comparison", ">= 60", "", false},
+		{"with age basis", map[string]interface{}{"compare": ">= 60", "age_basis": AgeAsOfYearStart}, AgeAsOfYearStart, false},
+		{"without age basis", map[string]interface{}{"compare": ">= 60"}, "", false},
+		{"unknown age basis", map[string]interface{}{"compare": ">= 60", "age_basis": "lunar"}, "", true},
+		{"missing compare", map[string]interface{}{"age_basis": AgeAsOfDate}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, basis, err := ParseNumericCondition(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseNumericCondition(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if basis != tt.wantBasis {
+				t.Errorf("ParseNumericCondition(%v) basis = %q, want %q", tt.value, basis, tt.wantBasis)
+			}
+		})
+	}
+}
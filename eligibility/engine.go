@@ -0,0 +1,318 @@
+package eligibility
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Profile is the applicant data a rule is evaluated against. A nil MonthlyIncome was never recorded.
+type Profile struct {
+	EmploymentStatus string
+	Sex              string
+	MaritalStatus    string
+	DateOfBirth      string
+	MonthlyIncome    *float64
+	Household        []Member
+}
+
+type Member struct {
+	Name             string
+	Relation         string
+	EmploymentStatus string
+	Sex              string
+	DateOfBirth      string
+	MonthlyIncome    *float64
+}
+
+// Trace records how a rule node was decided for a profile.
+type Trace struct {
+	Rule     string      `json:"rule"`
+	Key      string      `json:"key,omitempty"`
+	Expected interface{} `json:"expected,omitempty"`
+	Actual   interface{} `json:"actual,omitempty"`
+	Passed   bool        `json:"passed"`
+	Reason   string      `json:"reason"`
+	Children []Trace     `json:"children,omitempty"`
+}
+
+type outcome struct {
+	passed bool
+	actual interface{}
+	reason string
+}
+
+// Evaluator evaluates rules using the configured school levels as of an evaluation date.
+type Evaluator struct {
+	schoolLevels map[string]SchoolLevel
+	date         time.Time
+}
+
+type conditionFunc func(e Evaluator, value interface{}, profile Profile) (outcome, error)
+
+var conditions = map[string]conditionFunc{
+	"employment_status":        matchString("employment status", func(p Profile) string { return p.EmploymentStatus }),
+	"marital_status":           matchString("marital status", func(p Profile) string { return p.MaritalStatus }),
+	"sex":                      matchString("sex", func(p Profile) string { return p.Sex }),
+	"has_children":             hasChildren,
+	"age":                      matchNumber("age", applicantAge),
+	"household_size":           matchNumber("household size", householdSize),
+	"monthly_household_income": matchNumber("monthly household income", householdIncome),
+}
+
+func NewEvaluator(levels []SchoolLevel) Evaluator {
+	e := Evaluator{schoolLevels: make(map[string]SchoolLevel, len(levels))}
+	for _, level := range levels {
+		e.schoolLevels[strings.ToLower(level.Name)] = level
+	}
+	return e
+}
+
+// Evaluate reports whether the profile satisfies every node of the rule.
+func (e Evaluator) Evaluate(rule Rule, profile Profile) (bool, error) {
+	trace, err := e.Explain(rule, profile)
+	if err != nil {
+		return false, err
+	}
+	return trace.Passed, nil
+}
+
+// Explain evaluates every node of the rule and returns the pass/fail trace.
+// Groups are not short-circuited so the trace covers all conditions.
+func (e Evaluator) Explain(rule Rule, profile Profile) (Trace, error) {
+	switch {
+	case rule.All != nil:
+		return e.explainGroup("all", rule.All, profile)
+	case rule.Any != nil:
+		return e.explainGroup("any", rule.Any, profile)
+	case rule.Not != nil:
+		child, err := e.Explain(*rule.Not, profile)
+		if err != nil {
+			return Trace{}, err
+		}
+
+		trace := Trace{Rule: "not", Passed: !child.Passed, Children: []Trace{child}}
+		if trace.Passed {
+			trace.Reason = "negated rule did not match"
+		} else {
+			trace.Reason = "negated rule matched"
+		}
+		return trace, nil
+	}
+
+	keys := make([]string, 0, len(rule.Conditions))
+	for key := range rule.Conditions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	children := []Trace{}
+	for _, key := range keys {
+		child, err := e.explainCondition(key, rule.Conditions[key], profile)
+		if err != nil {
+			return Trace{}, err
+		}
+		children = append(children, child)
+	}
+
+	if len(children) == 1 {
+		return children[0], nil
+	}
+
+	trace := summarize("all", children)
+	trace.Rule = "conditions"
+	return trace, nil
+}
+
+func IsConditionKey(key string) bool {
+	_, ok := conditions[key]
+	return ok
+}
+
+func (e Evaluator) explainGroup(name string, rules []Rule, profile Profile) (Trace, error) {
+	children := []Trace{}
+	for _, r := range rules {
+		child, err := e.Explain(r, profile)
+		if err != nil {
+			return Trace{}, err
+		}
+		children = append(children, child)
+	}
+
+	return summarize(name, children), nil
+}
+
+func summarize(name string, children []Trace) Trace {
+	passed := 0
+	for _, child := range children {
+		if child.Passed {
+			passed++
+		}
+	}
+
+	trace := Trace{Rule: name, Children: children}
+	if name == "any" {
+		trace.Passed = passed > 0
+	} else {
+		trace.Passed = passed == len(children)
+	}
+	trace.Reason = fmt.Sprintf("%d of %d rules passed", passed, len(children))
+
+	return trace
+}
+
+func (e Evaluator) explainCondition(key string, value interface{}, profile Profile) (Trace, error) {
+	condition, ok := conditions[key]
+	if !ok {
+		return Trace{}, fmt.Errorf("unknown criteria key: %s", key)
+	}
+
+	result, err := condition(e, value, profile)
+	if err != nil {
+		return Trace{}, err
+	}
+
+	return Trace{
+		Rule:     "condition",
+		Key:      key,
+		Expected: value,
+		Actual:   result.actual,
+		Passed:   result.passed,
+		Reason:   result.reason,
+	}, nil
+}
+
+func matchString(name string, field func(Profile) string) conditionFunc {
+	return func(e Evaluator, value interface{}, profile Profile) (outcome, error) {
+		expected, ok := value.(string)
+		if !ok {
+			return outcome{}, fmt.Errorf("expected string criteria value, got %v", value)
+		}
+
+		actual := field(profile)
+		if strings.EqualFold(expected, actual) {
+			return outcome{passed: true, actual: actual, reason: fmt.Sprintf("%s is %s", name, expected)}, nil
+		}
+		return outcome{actual: actual, reason: fmt.Sprintf("%s %q is not %s", name, actual, expected)}, nil
+	}
+}
+
+// numberField returns the value a numeric condition is compared with, nil when the profile does not have it.
+type numberField func(e Evaluator, profile Profile, basis string) (*float64, error)
+
+func matchNumber(name string, field numberField) conditionFunc {
+	return func(e Evaluator, value interface{}, profile Profile) (outcome, error) {
+		comparisons, basis, err := ParseNumericCondition(value)
+		if err != nil {
+			return outcome{}, err
+		}
+
+		number, err := field(e, profile, basis)
+		if err != nil {
+			return outcome{}, err
+		}
+
+		// a value that is not known cannot be shown to satisfy the condition
+		if number == nil {
+			return outcome{reason: fmt.Sprintf("%s unknown", name)}, nil
+		}
+		actual := *number
+
+		matched := []string{}
+		for _, comparison := range comparisons {
+			if !comparison.Match(actual) {
+				return outcome{actual: actual, reason: fmt.Sprintf("%s %v is not %s", name, actual, comparison)}, nil
+			}
+			matched = append(matched, comparison.String())
+		}
+
+		return outcome{passed: true, actual: actual, reason: fmt.Sprintf("%s %v is %s", name, actual, strings.Join(matched, " and "))}, nil
+	}
+}
+
+func applicantAge(e Evaluator, profile Profile, basis string) (*float64, error) {
+	age, err := e.Age(profile.DateOfBirth, basis)
+	if err != nil {
+		return nil, err
+	}
+	value := float64(age)
+	return &value, nil
+}
+
+// household size counts the applicant together with the household members
+func householdSize(e Evaluator, profile Profile, basis string) (*float64, error) {
+	size := float64(len(profile.Household) + 1)
+	return &size, nil
+}
+
+// household income is unknown when the income of the applicant or of any household member is not recorded
+func householdIncome(e Evaluator, profile Profile, basis string) (*float64, error) {
+	if profile.MonthlyIncome == nil {
+		return nil, nil
+	}
+
+	income := *profile.MonthlyIncome
+	for _, member := range profile.Household {
+		if member.MonthlyIncome == nil {
+			return nil, nil
+		}
+		income += *member.MonthlyIncome
+	}
+	return &income, nil
+}
+
+func hasChildren(e Evaluator, value interface{}, profile Profile) (outcome, error) {
+	condition, ok := value.(map[string]interface{})
+	if !ok {
+		return outcome{}, fmt.Errorf("invalid has_children criteria: %v", value)
+	}
+
+	// only recognize school_level for now
+	schoolLevel, ok := condition["school_level"].(string)
+	if !ok {
+		return outcome{}, fmt.Errorf("invalid school_level criteria: %v", condition["school_level"])
+	}
+
+	name, err := ParseSchoolLevel(schoolLevel)
+	if err != nil {
+		return outcome{}, err
+	}
+
+	level, ok := e.schoolLevels[name]
+	if !ok {
+		return outcome{}, fmt.Errorf("unknown school level: %s", name)
+	}
+
+	basis, err := ageBasis(condition)
+	if err != nil {
+		return outcome{}, err
+	}
+
+	if len(profile.Household) == 0 {
+		return outcome{actual: []string{}, reason: "applicant has no household members"}, nil
+	}
+
+	actual := []string{}
+	reasons := []string{}
+	for _, member := range profile.Household {
+		age, err := e.Age(member.DateOfBirth, basis)
+		if err != nil {
+			return outcome{}, err
+		}
+
+		inLevel, err := level.Contains(member.DateOfBirth, age)
+		if err != nil {
+			return outcome{}, err
+		}
+
+		description := fmt.Sprintf("child %s age %d", member.Name, age)
+		actual = append(actual, description)
+		if inLevel {
+			return outcome{passed: true, actual: actual, reason: fmt.Sprintf("%s is %s level", description, level.Name)}, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("%s is not %s level", description, level.Name))
+	}
+
+	return outcome{actual: actual, reason: strings.Join(reasons, "; ")}, nil
+}
@@ -0,0 +1,176 @@
+package eligibility
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func testEvaluator() Evaluator {
+	levels := []SchoolLevel{
+		{Name: "Primary", BandType: BandAge, Min: intPtr(7), Max: intPtr(12)},
+		{Name: "Secondary", BandType: BandAge, Min: intPtr(13), Max: intPtr(16)},
+	}
+	return NewEvaluator(levels).WithDate(time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC))
+}
+
+// aged 64 on the evaluation date, with a child aged 10
+func testProfile() Profile {
+	return Profile{
+		EmploymentStatus: "unemployed",
+		Sex:              "female",
+		MaritalStatus:    "married",
+		DateOfBirth:      "1960-05-01",
+		MonthlyIncome:    floatPtr(500),
+		Household: []Member{
+			{Name: "Tan", Relation: "son", EmploymentStatus: "unemployed", DateOfBirth: "2014-03-01", MonthlyIncome: floatPtr(0)},
+		},
+	}
+}
+
+func TestEvaluateGroups(t *testing.T) {
+	unemployed := Condition("employment_status", "unemployed")
+	employed := Condition("employment_status", "employed")
+	senior := Condition("age", ">= 60")
+	young := Condition("age", "< 21")
+
+	tests := []struct {
+		name string
+		rule Rule
+		want bool
+	}{
+		{"single condition passes", unemployed, true},
+		{"single condition fails", employed, false},
+		{"all passes when every rule passes", AllOf(unemployed, senior), true},
+		{"all fails when one rule fails", AllOf(unemployed, young), false},
+		{"empty all passes", Rule{All: []Rule{}}, true},
+		{"any passes when one rule passes", AnyOf(employed, senior), true},
+		{"any fails when no rule passes", AnyOf(employed, young), false},
+		{"empty any fails", Rule{Any: []Rule{}}, false},
+		{"not inverts a pass", NotOf(unemployed), false},
+		{"not inverts a failure", NotOf(employed), true},
+		{"nested groups", AllOf(unemployed, AnyOf(young, NotOf(employed))), true},
+		{"several conditions in one leaf", Rule{Conditions: map[string]interface{}{"sex": "female", "marital_status": "single"}}, false},
+		{"numeric range", Condition("age", []interface{}{">= 60", "< 65"}), true},
+		{"household size counts the applicant", Condition("household_size", float64(2)), true},
+		{"household income sums every member", Condition("monthly_household_income", "< 2000"), true},
+		{"child in school level", Condition("has_children", map[string]interface{}{"school_level": "== primary"}), true},
+		{"child not in school level", Condition("has_children", map[string]interface{}{"school_level": "== secondary"}), false},
+	}
+
+	e := testEvaluator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Evaluate(tt.rule, testProfile())
+			if err != nil {
+				t.Fatalf("Evaluate returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateUnknownIncome(t *testing.T) {
+	profile := testProfile()
+	profile.Household[0].MonthlyIncome = nil
+
+	e := testEvaluator()
+	trace, err := e.Explain(Condition("monthly_household_income", "< 2000"), profile)
+	if err != nil {
+		t.Fatalf("Explain returned error: %v", err)
+	}
+	if trace.Passed {
+		t.Error("condition on an unknown household income passed")
+	}
+	if trace.Reason != "monthly household income unknown" {
+		t.Errorf("reason = %q, want %q", trace.Reason, "monthly household income unknown")
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"unknown criteria key", Condition("favourite_colour", "blue")},
+		{"unknown key inside a group", AnyOf(Condition("employment_status", "unemployed"), Condition("favourite_colour", "blue"))},
+		{"unknown school level", Condition("has_children", map[string]interface{}{"school_level": "== university"})},
+		{"empty school level", Condition("has_children", map[string]interface{}{"school_level": "=="})},
+		{"string condition with a number", Condition("employment_status", float64(1))},
+		{"malformed comparison", Condition("age", ">= old")},
+	}
+
+	e := testEvaluator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := e.Evaluate(tt.rule, testProfile()); err == nil {
+				t.Error("Evaluate returned no error")
+			}
+		})
+	}
+}
+
+func TestRuleJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr bool
+	}{
+		{"leaf", `{"employment_status":"unemployed"}`, false},
+		{"all", `{"all":[{"age":60},{"sex":"female"}]}`, false},
+		{"any", `{"any":[{"household_size":[1,2]},{"not":{"sex":"female"}}]}`, false},
+		{"group combined with a condition", `{"all":[],"sex":"female"}`, true},
+		{"not an object", `["age"]`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rule Rule
+			err := json.Unmarshal([]byte(tt.json), &rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			data, err := json.Marshal(rule)
+			if err != nil {
+				t.Fatalf("Marshal returned error: %v", err)
+			}
+			if string(data) != tt.json {
+				t.Errorf("round trip = %s, want %s", data, tt.json)
+			}
+		})
+	}
+}
+
+func TestRequiredConditions(t *testing.T) {
+	rule := AllOf(
+		Condition("employment_status", "unemployed"),
+		AnyOf(Condition("sex", "female")),
+		AnyOf(Condition("age", ">= 60"), Condition("marital_status", "widowed")),
+		NotOf(Condition("marital_status", "single")),
+	)
+
+	got := rule.RequiredConditions()
+	want := map[string]interface{}{"employment_status": "unemployed", "sex": "female"}
+	if len(got) != len(want) {
+		t.Fatalf("RequiredConditions = %v, want %v", got, want)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("RequiredConditions[%q] = %v, want %v", key, got[key], value)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package eligibility
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// criteria_key used when a whole rule tree is stored in a single criteria row
+const RuleKey = "rule"
+
+// Rule is a node of an eligibility rule tree. Exactly one of All, Any or Not
+// is set for a group node, otherwise the node is a leaf and every entry of
+// Conditions must match.
+//
+// In JSON a group is written as {"all": [...]}, {"any": [...]} or {"not": {...}}
+// and a leaf is the plain conditions object, e.g. {"employment_status": "unemployed"}.
+type Rule struct {
+	All        []Rule
+	Any        []Rule
+	Not        *Rule
+	Conditions map[string]interface{}
+}
+
+func AllOf(rules ...Rule) Rule {
+	return Rule{All: rules}
+}
+
+func AnyOf(rules ...Rule) Rule {
+	return Rule{Any: rules}
+}
+
+func NotOf(rule Rule) Rule {
+	return Rule{Not: &rule}
+}
+
+func Condition(key string, value interface{}) Rule {
+	return Rule{Conditions: map[string]interface{}{key: value}}
+}
+
+func (r Rule) IsGroup() bool {
+	return r.All != nil || r.Any != nil || r.Not != nil
+}
+
+// Each calls fn for every condition in the rule tree.
+func (r Rule) Each(fn func(key string, value interface{})) {
+	for _, child := range r.All {
+		child.Each(fn)
+	}
+	for _, child := range r.Any {
+		child.Each(fn)
+	}
+	if r.Not != nil {
+		r.Not.Each(fn)
+	}
+	for key, value := range r.Conditions {
+		fn(key, value)
+	}
+}
+
+// RequiredConditions returns the conditions every match of the rule must satisfy,
+// i.e. those reachable through "all" groups only. Used to pre-filter candidates.
+func (r Rule) RequiredConditions() map[string]interface{} {
+	required := make(map[string]interface{})
+	if len(r.Any) == 1 {
+		return r.Any[0].RequiredConditions()
+	}
+
+	for _, child := range r.All {
+		for key, value := range child.RequiredConditions() {
+			required[key] = value
+		}
+	}
+
+	if !r.IsGroup() {
+		for key, value := range r.Conditions {
+			required[key] = value
+		}
+	}
+
+	return required
+}
+
+func (r Rule) MarshalJSON() ([]byte, error) {
+	switch {
+	case r.All != nil:
+		return json.Marshal(map[string][]Rule{"all": r.All})
+	case r.Any != nil:
+		return json.Marshal(map[string][]Rule{"any": r.Any})
+	case r.Not != nil:
+		return json.Marshal(map[string]Rule{"not": *r.Not})
+	}
+
+	if r.Conditions == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(r.Conditions)
+}
+
+func (r *Rule) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("rule must be an object: %v", err)
+	}
+
+	*r = Rule{}
+	for _, group := range []string{"all", "any", "not"} {
+		value, ok := raw[group]
+		if !ok {
+			continue
+		}
+		if len(raw) != 1 {
+			return fmt.Errorf("rule group %q cannot be combined with other keys", group)
+		}
+
+		switch group {
+		case "all":
+			if err := json.Unmarshal(value, &r.All); err != nil {
+				return err
+			}
+			if r.All == nil {
+				r.All = []Rule{}
+			}
+		case "any":
+			if err := json.Unmarshal(value, &r.Any); err != nil {
+				return err
+			}
+			if r.Any == nil {
+				r.Any = []Rule{}
+			}
+		case "not":
+			r.Not = &Rule{}
+			if err := json.Unmarshal(value, r.Not); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	r.Conditions = make(map[string]interface{}, len(raw))
+	for key, value := range raw {
+		var condition interface{}
+		if err := json.Unmarshal(value, &condition); err != nil {
+			return err
+		}
+		r.Conditions[key] = condition
+	}
+
+	return nil
+}
+
+// ParseCriteria rebuilds a rule from a stored criteria row. Rows written before
+// rule trees existed hold a single condition key with its JSON encoded value.
+func ParseCriteria(key string, value string) (Rule, error) {
+	if key == RuleKey {
+		var rule Rule
+		if err := json.Unmarshal([]byte(value), &rule); err != nil {
+			return Rule{}, fmt.Errorf("invalid rule for criteria: %v", err)
+		}
+		return rule, nil
+	}
+
+	var condition interface{}
+	if err := json.Unmarshal([]byte(value), &condition); err != nil {
+		condition = value
+	}
+	return Condition(key, condition), nil
+}
@@ -0,0 +1,78 @@
+package eligibility
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	BandAge       = "age"
+	BandBirthYear = "birth_year"
+)
+
+// SchoolLevel is an education level with an inclusive age or birth-year band.
+// A nil bound leaves that side of the band open.
+type SchoolLevel struct {
+	Name     string
+	BandType string
+	Min      *int
+	Max      *int
+}
+
+// Contains reports whether a person born on dateOfBirth, whose age is already
+// worked out for the criterion, belongs to the level.
+func (l SchoolLevel) Contains(dateOfBirth string, age int) (bool, error) {
+	birthDate, err := time.Parse("2006-01-02", dateOfBirth)
+	if err != nil {
+		return false, fmt.Errorf("invalid date format: %v", err)
+	}
+
+	var value int
+	switch l.BandType {
+	case BandBirthYear:
+		value = birthDate.Year()
+	case BandAge:
+		value = age
+	default:
+		return false, fmt.Errorf("unknown band type %q for school level %s", l.BandType, l.Name)
+	}
+
+	if l.Min != nil && value < *l.Min {
+		return false, nil
+	}
+	if l.Max != nil && value > *l.Max {
+		return false, nil
+	}
+	return true, nil
+}
+
+// ParseSchoolLevel extracts the level name from a "== primary" style criteria value.
+func ParseSchoolLevel(value string) (string, error) {
+	level := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "==")))
+	if level == "" {
+		return "", fmt.Errorf("school level cannot be empty")
+	}
+	return level, nil
+}
+
+// SchoolLevelNames returns the school levels referenced by has_children conditions of the rule.
+func SchoolLevelNames(rule Rule) []string {
+	names := []string{}
+	rule.Each(func(key string, value interface{}) {
+		if key != "has_children" {
+			return
+		}
+
+		condition, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		if schoolLevel, ok := condition["school_level"].(string); ok {
+			if name, err := ParseSchoolLevel(schoolLevel); err == nil {
+				names = append(names, name)
+			}
+		}
+	})
+	return names
+}
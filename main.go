@@ -15,6 +15,10 @@ func main() {
 		log.Fatalf("Error loading .env file: %v", err)
 	}
 
+	if err := config.LoadMessages(config.MessagesDir); err != nil {
+		log.Fatalf("Load messages failed: %v", err)
+	}
+
 	db, err := config.InitDB()
 	if err != nil {
 		log.Fatalf("Init Database failed: %v", err)
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE schemes ADD COLUMN rule JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE schemes DROP COLUMN IF EXISTS rule;
+-- +goose StatementEnd
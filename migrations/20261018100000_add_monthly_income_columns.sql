@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE applicants ADD COLUMN monthly_income DECIMAl(16, 2);
+ALTER TABLE household_members ADD COLUMN monthly_income DECIMAl(16, 2);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE household_members DROP COLUMN IF EXISTS monthly_income;
+ALTER TABLE applicants DROP COLUMN IF EXISTS monthly_income;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE education_levels (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  band_type VARCHAR(255) NOT NULL,
+  min_value INTEGER,
+  max_value INTEGER,
+  deleted BOOLEAN DEFAULT false,
+  created_at TIMESTAMP DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC' + INTERVAL '8 hours'),
+  updated_at TIMESTAMP DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC' + INTERVAL '8 hours')
+);
+
+CREATE UNIQUE INDEX idx_education_levels_name ON education_levels (name) WHERE deleted = false;
+
+INSERT INTO education_levels (name, band_type, min_value, max_value) VALUES
+  ('pre-school', 'age', 3, 5),
+  ('primary', 'age', 6, 12),
+  ('secondary', 'age', 13, 18),
+  ('post-secondary', 'age', 19, 24);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_education_levels_name;
+DROP TABLE IF EXISTS education_levels;
+-- +goose StatementEnd
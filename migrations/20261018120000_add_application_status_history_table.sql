@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE application_status_history (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  application_id UUID NOT NULL,
+  previous_status VARCHAR(255),
+  new_status VARCHAR(255) NOT NULL,
+  actor VARCHAR(255) NOT NULL,
+  reason TEXT,
+  created_at TIMESTAMP DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC' + INTERVAL '8 hours')
+);
+
+ALTER TABLE application_status_history ADD CONSTRAINT fk_application_id FOREIGN KEY (application_id) REFERENCES applications(id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE application_status_history DROP CONSTRAINT fk_application_id;
+DROP TABLE IF EXISTS application_status_history;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE schemes ADD COLUMN application_policy VARCHAR(255) NOT NULL DEFAULT 'one_active';
+ALTER TABLE applications ADD COLUMN dedupe_key VARCHAR(255);
+
+-- the oldest open application of an applicant for a scheme holds the key, as when applications are rekeyed
+UPDATE applications SET dedupe_key = 'active' WHERE LOWER(status) NOT IN ('rejected', 'completed', 'cancelled') AND id IN (
+  SELECT DISTINCT ON (applicant_id, scheme_id) id FROM applications
+  WHERE LOWER(status) NOT IN ('rejected', 'completed', 'cancelled')
+  ORDER BY applicant_id, scheme_id, submitted_at ASC, id ASC
+);
+
+CREATE UNIQUE INDEX idx_applications_dedupe ON applications (applicant_id, scheme_id, dedupe_key);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_applications_dedupe;
+ALTER TABLE applications DROP COLUMN IF EXISTS dedupe_key;
+ALTER TABLE schemes DROP COLUMN IF EXISTS application_policy;
+-- +goose StatementEnd
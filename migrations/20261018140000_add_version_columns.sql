@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE applicants ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE schemes ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE applications ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE applications DROP COLUMN IF EXISTS version;
+ALTER TABLE schemes DROP COLUMN IF EXISTS version;
+ALTER TABLE applicants DROP COLUMN IF EXISTS version;
+-- +goose StatementEnd
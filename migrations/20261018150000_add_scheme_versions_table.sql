@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE scheme_versions (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  scheme_id UUID NOT NULL,
+  number INTEGER,
+  status VARCHAR(255) NOT NULL,
+  definition JSONB NOT NULL,
+  published_at TIMESTAMP,
+  retired_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC' + INTERVAL '8 hours'),
+  updated_at TIMESTAMP DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC' + INTERVAL '8 hours')
+);
+
+ALTER TABLE scheme_versions ADD CONSTRAINT fk_scheme_id FOREIGN KEY (scheme_id) REFERENCES schemes(id);
+
+-- drafts are numbered when published, a scheme has at most one draft and one published version
+CREATE UNIQUE INDEX idx_scheme_versions_number ON scheme_versions (scheme_id, number);
+CREATE UNIQUE INDEX idx_scheme_versions_draft ON scheme_versions (scheme_id) WHERE status = 'draft';
+CREATE UNIQUE INDEX idx_scheme_versions_published ON scheme_versions (scheme_id) WHERE status = 'published';
+
+ALTER TABLE applications ADD COLUMN scheme_version_id UUID;
+ALTER TABLE applications ADD CONSTRAINT fk_scheme_version_id FOREIGN KEY (scheme_version_id) REFERENCES scheme_versions(id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE applications DROP CONSTRAINT fk_scheme_version_id;
+ALTER TABLE applications DROP COLUMN IF EXISTS scheme_version_id;
+ALTER TABLE scheme_versions DROP CONSTRAINT fk_scheme_id;
+DROP TABLE IF EXISTS scheme_versions;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE schemes ADD COLUMN effective_from DATE;
+ALTER TABLE schemes ADD COLUMN effective_to DATE;
+ALTER TABLE schemes ADD COLUMN application_from DATE;
+ALTER TABLE schemes ADD COLUMN application_to DATE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE schemes DROP COLUMN IF EXISTS application_to;
+ALTER TABLE schemes DROP COLUMN IF EXISTS application_from;
+ALTER TABLE schemes DROP COLUMN IF EXISTS effective_to;
+ALTER TABLE schemes DROP COLUMN IF EXISTS effective_from;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE schemes ADD COLUMN budget DECIMAL(16, 2);
+ALTER TABLE schemes ADD COLUMN max_recipients INTEGER;
+ALTER TABLE schemes ADD COLUMN budget_policy VARCHAR(255) NOT NULL DEFAULT 'block';
+ALTER TABLE applications ADD COLUMN committed_amount DECIMAL(16, 2);
+
+-- applications approved so far, including completed ones that were approved, hold their benefits
+UPDATE applications a SET committed_amount = (
+  SELECT COALESCE(SUM(ad.benefit_amount), 0) FROM application_details ad WHERE ad.application_id = a.id
+) WHERE LOWER(a.status) = 'approved' OR (LOWER(a.status) = 'completed' AND EXISTS (
+  SELECT 1 FROM application_status_history h WHERE h.application_id = a.id AND h.new_status = 'approved'
+));
+
+CREATE INDEX idx_applications_committed ON applications (scheme_id) WHERE committed_amount IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_applications_committed;
+ALTER TABLE applications DROP COLUMN IF EXISTS committed_amount;
+ALTER TABLE schemes DROP COLUMN IF EXISTS budget_policy;
+ALTER TABLE schemes DROP COLUMN IF EXISTS max_recipients;
+ALTER TABLE schemes DROP COLUMN IF EXISTS budget;
+-- +goose StatementEnd
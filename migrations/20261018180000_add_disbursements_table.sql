@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE benefits ADD COLUMN frequency VARCHAR(255) NOT NULL DEFAULT 'one_off';
+ALTER TABLE benefits ADD COLUMN instalments INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE benefits ADD COLUMN start_rule VARCHAR(255) NOT NULL DEFAULT 'on_approval';
+ALTER TABLE benefits ADD COLUMN start_date DATE;
+
+ALTER TABLE application_details ADD COLUMN frequency VARCHAR(255) NOT NULL DEFAULT 'one_off';
+ALTER TABLE application_details ADD COLUMN instalments INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE application_details ADD COLUMN start_rule VARCHAR(255) NOT NULL DEFAULT 'on_approval';
+ALTER TABLE application_details ADD COLUMN start_date DATE;
+
+CREATE TABLE disbursements (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  application_id UUID NOT NULL,
+  application_detail_id UUID NOT NULL,
+  benefit_id UUID,
+  benefit_name VARCHAR(255) NOT NULL,
+  instalment INTEGER NOT NULL,
+  amount DECIMAL(16, 2) NOT NULL,
+  due_date DATE NOT NULL,
+  status VARCHAR(255) NOT NULL DEFAULT 'scheduled',
+  created_at TIMESTAMP DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC' + INTERVAL '8 hours'),
+  updated_at TIMESTAMP DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC' + INTERVAL '8 hours')
+);
+
+ALTER TABLE disbursements ADD CONSTRAINT fk_application_id FOREIGN KEY (application_id) REFERENCES applications(id);
+ALTER TABLE disbursements ADD CONSTRAINT fk_application_detail_id FOREIGN KEY (application_detail_id) REFERENCES application_details(id);
+ALTER TABLE disbursements ADD CONSTRAINT fk_benefit_id FOREIGN KEY (benefit_id) REFERENCES benefits(id);
+CREATE UNIQUE INDEX idx_disbursements_instalment ON disbursements (application_detail_id, instalment);
+CREATE INDEX idx_disbursements_application ON disbursements (application_id, due_date);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_disbursements_application;
+DROP INDEX IF EXISTS idx_disbursements_instalment;
+ALTER TABLE disbursements DROP CONSTRAINT fk_application_id;
+ALTER TABLE disbursements DROP CONSTRAINT fk_application_detail_id;
+ALTER TABLE disbursements DROP CONSTRAINT fk_benefit_id;
+DROP TABLE IF EXISTS disbursements;
+
+ALTER TABLE application_details DROP COLUMN IF EXISTS start_date;
+ALTER TABLE application_details DROP COLUMN IF EXISTS start_rule;
+ALTER TABLE application_details DROP COLUMN IF EXISTS instalments;
+ALTER TABLE application_details DROP COLUMN IF EXISTS frequency;
+
+ALTER TABLE benefits DROP COLUMN IF EXISTS start_date;
+ALTER TABLE benefits DROP COLUMN IF EXISTS start_rule;
+ALTER TABLE benefits DROP COLUMN IF EXISTS instalments;
+ALTER TABLE benefits DROP COLUMN IF EXISTS frequency;
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE ledger_entries (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  disbursement_id UUID NOT NULL,
+  application_id UUID NOT NULL,
+  application_detail_id UUID NOT NULL,
+  benefit_id UUID,
+  amount DECIMAL(16, 2) NOT NULL,
+  entry_date DATE NOT NULL,
+  reference VARCHAR(255) NOT NULL,
+  status VARCHAR(255) NOT NULL,
+  reversal_of UUID,
+  reversed_at TIMESTAMP,
+  idempotency_key VARCHAR(255) NOT NULL,
+  request_hash VARCHAR(64) NOT NULL,
+  created_at TIMESTAMP DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC' + INTERVAL '8 hours'),
+  updated_at TIMESTAMP DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC' + INTERVAL '8 hours')
+);
+
+ALTER TABLE ledger_entries ADD CONSTRAINT fk_disbursement_id FOREIGN KEY (disbursement_id) REFERENCES disbursements(id);
+ALTER TABLE ledger_entries ADD CONSTRAINT fk_application_id FOREIGN KEY (application_id) REFERENCES applications(id);
+ALTER TABLE ledger_entries ADD CONSTRAINT fk_application_detail_id FOREIGN KEY (application_detail_id) REFERENCES application_details(id);
+ALTER TABLE ledger_entries ADD CONSTRAINT fk_benefit_id FOREIGN KEY (benefit_id) REFERENCES benefits(id);
+ALTER TABLE ledger_entries ADD CONSTRAINT fk_reversal_of FOREIGN KEY (reversal_of) REFERENCES ledger_entries(id);
+
+CREATE UNIQUE INDEX idx_ledger_entries_idempotency ON ledger_entries (idempotency_key);
+-- a disbursement has at most one payout in flight or paid, and a payout is reversed at most once
+CREATE UNIQUE INDEX idx_ledger_entries_open_payout ON ledger_entries (disbursement_id) WHERE status IN ('scheduled', 'paid') AND reversed_at IS NULL;
+CREATE UNIQUE INDEX idx_ledger_entries_reversal ON ledger_entries (reversal_of) WHERE reversal_of IS NOT NULL;
+CREATE INDEX idx_ledger_entries_date ON ledger_entries (entry_date);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_ledger_entries_date;
+DROP INDEX IF EXISTS idx_ledger_entries_reversal;
+DROP INDEX IF EXISTS idx_ledger_entries_open_payout;
+DROP INDEX IF EXISTS idx_ledger_entries_idempotency;
+ALTER TABLE ledger_entries DROP CONSTRAINT fk_reversal_of;
+ALTER TABLE ledger_entries DROP CONSTRAINT fk_benefit_id;
+ALTER TABLE ledger_entries DROP CONSTRAINT fk_application_detail_id;
+ALTER TABLE ledger_entries DROP CONSTRAINT fk_application_id;
+ALTER TABLE ledger_entries DROP CONSTRAINT fk_disbursement_id;
+DROP TABLE IF EXISTS ledger_entries;
+-- +goose StatementEnd
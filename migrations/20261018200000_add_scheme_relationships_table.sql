@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE scheme_relationships (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  scheme_id UUID NOT NULL,
+  related_scheme_id UUID NOT NULL,
+  relation VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC' + INTERVAL '8 hours'),
+  CHECK (scheme_id <> related_scheme_id)
+);
+
+ALTER TABLE scheme_relationships ADD CONSTRAINT fk_scheme_id FOREIGN KEY (scheme_id) REFERENCES schemes(id);
+ALTER TABLE scheme_relationships ADD CONSTRAINT fk_related_scheme_id FOREIGN KEY (related_scheme_id) REFERENCES schemes(id);
+CREATE UNIQUE INDEX idx_scheme_relationships_pair ON scheme_relationships (scheme_id, related_scheme_id);
+CREATE INDEX idx_scheme_relationships_related ON scheme_relationships (related_scheme_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_scheme_relationships_related;
+DROP INDEX IF EXISTS idx_scheme_relationships_pair;
+ALTER TABLE scheme_relationships DROP CONSTRAINT fk_related_scheme_id;
+ALTER TABLE scheme_relationships DROP CONSTRAINT fk_scheme_id;
+DROP TABLE IF EXISTS scheme_relationships;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE ledger_postings (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  entry_id UUID NOT NULL,
+  account VARCHAR(255) NOT NULL,
+  side VARCHAR(255) NOT NULL,
+  amount DECIMAL(16, 2) NOT NULL,
+  created_at TIMESTAMP DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC' + INTERVAL '8 hours'),
+  CHECK (side IN ('debit', 'credit')),
+  CHECK (amount > 0)
+);
+
+ALTER TABLE ledger_postings ADD CONSTRAINT fk_entry_id FOREIGN KEY (entry_id) REFERENCES ledger_entries(id);
+CREATE INDEX idx_ledger_postings_entry ON ledger_postings (entry_id);
+
+-- paid payouts move the amount from the scheme fund to the beneficiary, reversals move it back
+INSERT INTO ledger_postings (entry_id, account, side, amount)
+SELECT id, 'beneficiary_payable', 'debit', ABS(amount) FROM ledger_entries WHERE status = 'paid' AND amount <> 0
+UNION ALL
+SELECT id, 'scheme_fund', 'credit', ABS(amount) FROM ledger_entries WHERE status = 'paid' AND amount <> 0
+UNION ALL
+SELECT id, 'scheme_fund', 'debit', ABS(amount) FROM ledger_entries WHERE status = 'reversed' AND amount <> 0
+UNION ALL
+SELECT id, 'beneficiary_payable', 'credit', ABS(amount) FROM ledger_entries WHERE status = 'reversed' AND amount <> 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_ledger_postings_entry;
+ALTER TABLE ledger_postings DROP CONSTRAINT fk_entry_id;
+DROP TABLE IF EXISTS ledger_postings;
+-- +goose StatementEnd
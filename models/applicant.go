@@ -5,11 +5,14 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"oneCV/config"
+	"oneCV/eligibility"
 	"oneCV/utils"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type Applicant struct {
@@ -19,7 +22,9 @@ type Applicant struct {
 	Sex              string            `json:"sex" binding:"required"`
 	DateOfBirth      string            `json:"date_of_birth" binding:"required"`
 	MaritalStatus    string            `json:"marital_status"`
+	MonthlyIncome    *float64          `json:"monthly_income"`
 	HouseholdMembers []HouseholdMember `json:"household"`
+	Version          int               `json:"-"`
 }
 
 type HouseholdMember struct {
@@ -29,14 +34,63 @@ type HouseholdMember struct {
 	Sex              *string   `json:"sex" `
 	Relation         *string   `json:"relation"`
 	DateOfBirth      *string   `json:"date_of_birth"`
+	MonthlyIncome    *float64  `json:"monthly_income"`
 }
 
-func (s *Applicant) GetAllApplicants(ctx context.Context, db *sql.DB) (data []Applicant, err error) {
-	return s.FetchApplicant(ctx, db, "")
+var applicantSortFields = map[string]SortField{
+	"name":          {Column: "a.name", Cast: "text"},
+	"date_of_birth": {Column: "a.date_of_birth", Cast: "date"},
+	"created_at":    {Column: "a.created_at", Cast: "timestamp"},
+}
+
+func applicantNotFound(id uuid.UUID) *Error {
+	return NotFoundError(CodeApplicantNotFound, config.APPLICANT_NOT_FOUND, map[string]interface{}{"id": id})
+}
+
+// ApplicantFilter narrows the applicant list, empty fields are ignored.
+type ApplicantFilter struct {
+	EmploymentStatus string
+	MaritalStatus    string
+	Name             string
+}
+
+func (f ApplicantFilter) Apply(q *ListQuery) {
+	if f.EmploymentStatus != "" {
+		q.Where("a.employment_status = ?", strings.ToLower(f.EmploymentStatus))
+	}
+	if f.MaritalStatus != "" {
+		q.Where("a.marital_status = ?", strings.ToLower(f.MaritalStatus))
+	}
+	if f.Name != "" {
+		q.Where("a.name ILIKE ? || '%'", likePrefix(f.Name))
+	}
+}
+
+func (s *Applicant) GetAllApplicants(ctx context.Context, db *sql.DB, q ListQuery) ([]Applicant, Page, error) {
+	from := `FROM applicants a WHERE a.deleted = false`
+	page := Page{Limit: q.Limit}
+
+	ids, nextCursor, err := q.fetchPage(ctx, db, from, "a.id", applicantSortFields, "name")
+	if err != nil {
+		return nil, page, err
+	}
+	page.NextCursor = nextCursor
+
+	page.Total, err = q.count(ctx, db, from)
+	if err != nil {
+		return nil, page, err
+	}
+
+	applicants, err := s.FetchApplicant(ctx, db, ` AND a.id = ANY($1)`, pq.Array(idStrings(ids)))
+	if err != nil {
+		return nil, page, err
+	}
+
+	return orderByIds(applicants, ids, func(a Applicant) uuid.UUID { return a.Id }), page, nil
 }
 
 func (s *Applicant) FetchApplicant(ctx context.Context, db *sql.DB, whereClause string, args ...interface{}) (data []Applicant, err error) {
-	query := `SELECT a.id, a.name, a.employment_status, a.sex, TO_CHAR(a.date_of_birth, 'YYYY-MM-DD') as date_of_birth, a.marital_status, hm.id as h_id, hm.name as h_name, hm.relation, TO_CHAR(hm.date_of_birth, 'YYYY-MM-DD') as hm_date_of_birth, hm.employment_status as h_employment_status, hm.sex as h_sex FROM applicants a LEFT JOIN household_members hm ON a.id = hm.applicant_id WHERE a.deleted = false ` + whereClause
+	query := `SELECT a.id, a.version, a.name, a.employment_status, a.sex, TO_CHAR(a.date_of_birth, 'YYYY-MM-DD') as date_of_birth, a.marital_status, a.monthly_income, hm.id as h_id, hm.name as h_name, hm.relation, TO_CHAR(hm.date_of_birth, 'YYYY-MM-DD') as hm_date_of_birth, hm.employment_status as h_employment_status, hm.sex as h_sex, hm.monthly_income as h_monthly_income FROM applicants a LEFT JOIN household_members hm ON a.id = hm.applicant_id WHERE a.deleted = false ` + whereClause + ` ORDER BY a.created_at, a.id, hm.created_at`
 
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -46,11 +100,12 @@ func (s *Applicant) FetchApplicant(ctx context.Context, db *sql.DB, whereClause
 	defer rows.Close()
 
 	var applicantsMap = make(map[uuid.UUID]*Applicant)
+	var order []uuid.UUID
 	for rows.Next() {
 		var applicant Applicant
 		var householdMember HouseholdMember
 
-		err := rows.Scan(&applicant.Id, &applicant.Name, &applicant.EmploymentStatus, &applicant.Sex, &applicant.DateOfBirth, &applicant.MaritalStatus, &householdMember.Id, &householdMember.Name, &householdMember.Relation, &householdMember.DateOfBirth, &householdMember.EmploymentStatus, &householdMember.Sex)
+		err := rows.Scan(&applicant.Id, &applicant.Version, &applicant.Name, &applicant.EmploymentStatus, &applicant.Sex, &applicant.DateOfBirth, &applicant.MaritalStatus, &applicant.MonthlyIncome, &householdMember.Id, &householdMember.Name, &householdMember.Relation, &householdMember.DateOfBirth, &householdMember.EmploymentStatus, &householdMember.Sex, &householdMember.MonthlyIncome)
 		if err != nil {
 			log.Println("Error scanning row:", err)
 			return nil, err
@@ -73,11 +128,12 @@ func (s *Applicant) FetchApplicant(ctx context.Context, db *sql.DB, whereClause
 
 			applicant.DateOfBirth = utils.DateFormat(applicant.DateOfBirth, "2006-01-02")
 			applicantsMap[applicant.Id] = &applicant
+			order = append(order, applicant.Id)
 		}
 	}
 
-	for _, applicant := range applicantsMap {
-		data = append(data, *applicant)
+	for _, id := range order {
+		data = append(data, *applicantsMap[id])
 	}
 
 	if err := rows.Err(); err != nil {
@@ -96,10 +152,10 @@ func (s *Applicant) CreateApplicant(ctx context.Context, db *sql.DB) (err error)
 	}
 	defer tx.Rollback()
 
-	query := `INSERT INTO applicants (name, employment_status, sex, date_of_birth, marital_status) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	query := `INSERT INTO applicants (name, employment_status, sex, date_of_birth, marital_status, monthly_income) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
 
 	var applicantId uuid.UUID
-	err = tx.QueryRowContext(ctx, query, s.Name, strings.ToLower(s.EmploymentStatus), strings.ToLower(s.Sex), strings.ToLower(s.DateOfBirth), strings.ToLower(s.MaritalStatus)).Scan(&applicantId)
+	err = tx.QueryRowContext(ctx, query, s.Name, strings.ToLower(s.EmploymentStatus), strings.ToLower(s.Sex), strings.ToLower(s.DateOfBirth), strings.ToLower(s.MaritalStatus), s.MonthlyIncome).Scan(&applicantId)
 	if err != nil {
 		log.Println("Error inserting applicant:", err)
 		return err
@@ -122,16 +178,9 @@ func (s *Applicant) CreateApplicant(ctx context.Context, db *sql.DB) (err error)
 }
 
 func (s *Applicant) CreateHouseholdMembers(ctx context.Context, tx *sql.Tx) (err error) {
-	if len(s.HouseholdMembers) > 0 {
-		for _, member := range s.HouseholdMembers {
-			memberQuery := `INSERT INTO household_members (applicant_id, name, date_of_birth, relation, employment_status, sex) VALUES ($1, $2, $3, $4, $5, $6)`
-
-			log.Printf("query %+v s.Id %+v ", memberQuery, s.Id)
-			_, err := tx.ExecContext(ctx, memberQuery, s.Id, member.Name, member.DateOfBirth, member.Relation, strings.ToLower(*member.EmploymentStatus), strings.ToLower(*member.Sex))
-			if err != nil {
-				log.Println("Error inserting household member:", err)
-				return err
-			}
+	for i := range s.HouseholdMembers {
+		if err := s.HouseholdMembers[i].insert(ctx, tx, s.Id); err != nil {
+			return err
 		}
 	}
 
@@ -147,26 +196,42 @@ func (s *Applicant) UpdateApplicant(ctx context.Context, db *sql.DB) (err error)
 	defer tx.Rollback()
 
 	// Update the applicant record
-	query := `UPDATE applicants SET name = $1, employment_status = $2, sex = $3, date_of_birth = $4, marital_status = $5, updated_at = $6 WHERE id = $7 AND deleted = false`
-	_, err = tx.ExecContext(ctx, query, s.Name, strings.ToLower(s.EmploymentStatus), strings.ToLower(s.Sex), s.DateOfBirth, strings.ToLower(s.MaritalStatus), time.Now(), s.Id)
+	query := `UPDATE applicants SET name = $1, employment_status = $2, sex = $3, date_of_birth = $4, marital_status = $5, monthly_income = $6, updated_at = $7, version = version + 1 WHERE id = $8 AND deleted = false AND ($9 = 0 OR version = $9) RETURNING version`
+	err = tx.QueryRowContext(ctx, query, s.Name, strings.ToLower(s.EmploymentStatus), strings.ToLower(s.Sex), s.DateOfBirth, strings.ToLower(s.MaritalStatus), s.MonthlyIncome, time.Now(), s.Id, s.Version).Scan(&s.Version)
+	if err == sql.ErrNoRows {
+		return missedWrite(s.Id, s.Version, applicantNotFound(s.Id))
+	}
 	if err != nil {
 		log.Println("Error updating applicant:", err)
 		return err
 	}
 
-	// Remove existing household members for the applicant
-	deleteQuery := `DELETE FROM household_members WHERE applicant_id = $1`
-	_, err = tx.ExecContext(ctx, deleteQuery, s.Id)
+	// Members sent with their id are updated in place so references to them stay valid,
+	// the others are new. Existing members left out of the request are removed.
+	keep := []string{}
+	for _, member := range s.HouseholdMembers {
+		if member.Id != uuid.Nil {
+			keep = append(keep, member.Id.String())
+		}
+	}
+
+	deleteQuery := `DELETE FROM household_members WHERE applicant_id = $1 AND NOT (id = ANY($2))`
+	_, err = tx.ExecContext(ctx, deleteQuery, s.Id, pq.Array(keep))
 	if err != nil {
 		log.Println("Error deleting old household members:", err)
 		return err
 	}
 
-	// Insert updated household members
-	err = s.CreateHouseholdMembers(ctx, tx)
-	if err != nil {
-		log.Println("Error create household member", err)
-		return err
+	for i := range s.HouseholdMembers {
+		member := &s.HouseholdMembers[i]
+		if member.Id == uuid.Nil {
+			err = member.insert(ctx, tx, s.Id)
+		} else {
+			err = member.update(ctx, tx, s.Id)
+		}
+		if err != nil {
+			return err
+		}
 	}
 
 	// Commit the transaction
@@ -187,65 +252,36 @@ func (s *Applicant) GetApplicantById(ctx context.Context, db *sql.DB) error {
 	}
 
 	if len(applicants) == 0 {
-		return fmt.Errorf("applicant not found: %v", s.Id)
+		return applicantNotFound(s.Id)
 	}
 
 	*s = applicants[0]
 	return nil
 }
 
-func (s *Applicant) GetApplicantCriteriaData(ctx context.Context, db *sql.DB) ([]CriteriaData, error) {
-	data := []CriteriaData{}
-
-	// store applicant employment status
-	employmentStatus := CriteriaData{}
-	employmentStatus.CriteriaKey = "employment_status"
-	employmentStatus.CriteriaValue = `"` + s.EmploymentStatus + `"`
-	data = append(data, employmentStatus)
-
-	// store applicant sex
-	sex := CriteriaData{}
-	sex.CriteriaKey = "sex"
-	sex.CriteriaValue = `"` + s.Sex + `"`
-	data = append(data, sex)
-
-	// store applicant marital status
-	maritalStatus := CriteriaData{}
-	maritalStatus.CriteriaKey = "marital_status"
-	maritalStatus.CriteriaValue = `"` + s.MaritalStatus + `"`
-	data = append(data, maritalStatus)
-
-	isPrimary := false
-	isSecondary := false
-
-	for _, v := range s.HouseholdMembers {
-		age, err := utils.CalculateAge(*v.DateOfBirth)
-		if err != nil {
-			return []CriteriaData{}, err
-		}
-
-		if age >= 6 && age <= 12 {
-			isPrimary = true
-		} else if age >= 13 && age <= 18 {
-			isSecondary = true
-		}
-	}
-
-	if isPrimary {
-		maritalStatus := CriteriaData{}
-		maritalStatus.CriteriaKey = "has_children"
-		maritalStatus.CriteriaValue = `{"school_level":"== primary"}`
-		data = append(data, maritalStatus)
+// Profile converts the applicant into the data used by the eligibility engine.
+func (s *Applicant) Profile() eligibility.Profile {
+	profile := eligibility.Profile{
+		EmploymentStatus: s.EmploymentStatus,
+		Sex:              s.Sex,
+		MaritalStatus:    s.MaritalStatus,
+		DateOfBirth:      s.DateOfBirth,
+		MonthlyIncome:    s.MonthlyIncome,
+		Household:        []eligibility.Member{},
 	}
 
-	if isSecondary {
-		maritalStatus := CriteriaData{}
-		maritalStatus.CriteriaKey = "has_children"
-		maritalStatus.CriteriaValue = `{"school_level":"== secondary"}`
-		data = append(data, maritalStatus)
+	for _, v := range s.HouseholdMembers {
+		profile.Household = append(profile.Household, eligibility.Member{
+			Name:             utils.StringValue(v.Name),
+			Relation:         utils.StringValue(v.Relation),
+			EmploymentStatus: utils.StringValue(v.EmploymentStatus),
+			Sex:              utils.StringValue(v.Sex),
+			DateOfBirth:      utils.StringValue(v.DateOfBirth),
+			MonthlyIncome:    v.MonthlyIncome,
+		})
 	}
 
-	return data, nil
+	return profile
 }
 
 func (s *Applicant) CheckApplicantExist(ctx context.Context, db *sql.DB) error {
@@ -256,7 +292,7 @@ func (s *Applicant) CheckApplicantExist(ctx context.Context, db *sql.DB) error {
 		return fmt.Errorf("error checking applicant existence: %v", err)
 	}
 	if !exists {
-		return fmt.Errorf("applicant %s does not exist", s.Id)
+		return applicantNotFound(s.Id)
 	}
 
 	return nil
@@ -270,8 +306,8 @@ func (s *Applicant) DeleteApplicant(ctx context.Context, db *sql.DB) error {
 	}
 	defer tx.Rollback()
 
-	query := `UPDATE applicants SET deleted = $1, updated_at = $2 WHERE id = $3 AND deleted = false`
-	result, err := tx.ExecContext(ctx, query, true, time.Now(), s.Id)
+	query := `UPDATE applicants SET deleted = $1, updated_at = $2, version = version + 1 WHERE id = $3 AND deleted = false AND ($4 = 0 OR version = $4)`
+	result, err := tx.ExecContext(ctx, query, true, time.Now(), s.Id, s.Version)
 	if err != nil {
 		log.Println("Error updating applicant:", err)
 		return err
@@ -282,7 +318,7 @@ func (s *Applicant) DeleteApplicant(ctx context.Context, db *sql.DB) error {
 		return fmt.Errorf("error checking rows affected: %v", err)
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("no applicant found with ID %s or applicant already deleted", s.Id)
+		return missedWrite(s.Id, s.Version, applicantNotFound(s.Id))
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"oneCV/config"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// unique index on (applicant_id, scheme_id, dedupe_key) guarding against concurrent submissions
+const dedupeIndex = "idx_applications_dedupe"
+
+// statuses that no longer count as an active application
+var inactiveStatuses = map[string]bool{
+	config.StatusRejected:  true,
+	config.StatusCompleted: true,
+	config.StatusCancelled: true,
+}
+
+// dedupeKey returns the key stored with an application so the unique index enforces the policy.
+// A nil key never conflicts, which leaves the application out of the check.
+func dedupeKey(policy string, status string, submittedAt time.Time) *string {
+	status = strings.ToLower(status)
+
+	var key string
+	switch policy {
+	case config.PolicyOneActive:
+		if inactiveStatuses[status] {
+			return nil
+		}
+		key = "active"
+	case config.PolicyOnePerYear:
+		if status == config.StatusCancelled {
+			return nil
+		}
+		key = strconv.Itoa(submittedAt.Year())
+	default:
+		return nil
+	}
+
+	return &key
+}
+
+// keyedApplication is an application of a scheme with what its dedupe key is computed from.
+type keyedApplication struct {
+	Id          uuid.UUID
+	ApplicantId uuid.UUID
+	Status      string
+	SubmittedAt time.Time
+}
+
+// fetchKeyedApplications returns the applications of the scheme, of one applicant when given, oldest first.
+func fetchKeyedApplications(ctx context.Context, db querier, schemeId uuid.UUID, applicantId *uuid.UUID, lock bool) ([]keyedApplication, error) {
+	query := `SELECT id, applicant_id, status, submitted_at FROM applications WHERE scheme_id = $1 AND ($2::UUID IS NULL OR applicant_id = $2) ORDER BY submitted_at, id`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+
+	rows, err := db.QueryContext(ctx, query, schemeId, applicantId)
+	if err != nil {
+		log.Println("Error querying applications of scheme:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	applications := []keyedApplication{}
+	for rows.Next() {
+		var a keyedApplication
+		if err := rows.Scan(&a.Id, &a.ApplicantId, &a.Status, &a.SubmittedAt); err != nil {
+			log.Println("Error scanning application row:", err)
+			return nil, err
+		}
+		applications = append(applications, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return nil, err
+	}
+
+	return applications, nil
+}
+
+// CheckDuplicate returns a conflict error with the existing application id when an application with the same key already exists.
+// The key of each existing application is computed from the current policy rather than read from the stored one, so
+// applications left without a key when the policy changed are still counted.
+func (ac *Application) CheckDuplicate(ctx context.Context, db *sql.DB, policy string) error {
+	key := dedupeKey(policy, ac.Status, ac.SubmittedAt)
+	if key == nil {
+		return nil
+	}
+
+	existing, err := fetchKeyedApplications(ctx, db, ac.SchemeID, &ac.ApplicantID, false)
+	if err != nil {
+		return fmt.Errorf("error checking duplicate application: %v", err)
+	}
+
+	for _, application := range existing {
+		if other := dedupeKey(policy, application.Status, application.SubmittedAt); other != nil && *other == *key {
+			return ConflictError(CodeDuplicateApplication, config.DUPLICATE_APPLICATION, map[string]interface{}{
+				"application_id": application.Id,
+				"policy":         policy,
+			})
+		}
+	}
+	return nil
+}
+
+// rekeyApplications recomputes the dedupe keys of the applications of the scheme for a new policy. When
+// applications made under the old policy share a key, the oldest keeps it and the others are left without one.
+func (s *Scheme) rekeyApplications(ctx context.Context, tx *sql.Tx, policy string) error {
+	applications, err := fetchKeyedApplications(ctx, tx, s.Id, nil, true)
+	if err != nil {
+		return err
+	}
+
+	ids := []string{}
+	keys := []string{}
+	taken := make(map[string]bool)
+	for _, application := range applications {
+		key := dedupeKey(policy, application.Status, application.SubmittedAt)
+		if key == nil || taken[application.ApplicantId.String()+"/"+*key] {
+			continue
+		}
+		taken[application.ApplicantId.String()+"/"+*key] = true
+		ids = append(ids, application.Id.String())
+		keys = append(keys, *key)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE applications SET dedupe_key = NULL WHERE scheme_id = $1`, s.Id); err != nil {
+		log.Println("Error clearing dedupe keys:", err)
+		return err
+	}
+
+	query := `UPDATE applications a SET dedupe_key = k.key FROM UNNEST($1::UUID[], $2::VARCHAR[]) AS k(id, key) WHERE a.id = k.id`
+	if _, err := tx.ExecContext(ctx, query, pq.Array(ids), pq.Array(keys)); err != nil {
+		log.Println("Error updating dedupe keys:", err)
+		return err
+	}
+	return nil
+}
+
+func isDedupeViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == dedupeIndex
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"oneCV/config"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// allowed next statuses for each application status, an approval over the scheme budget is waitlisted
+// instead when the scheme allows it
+var statusTransitions = map[string][]string{
+	config.StatusPending:    {config.StatusInProgress, config.StatusOnHold, config.StatusCancelled},
+	config.StatusInProgress: {config.StatusApproved, config.StatusRejected, config.StatusOnHold, config.StatusCancelled},
+	config.StatusOnHold:     {config.StatusPending, config.StatusInProgress, config.StatusCancelled},
+	config.StatusApproved:   {config.StatusCompleted, config.StatusCancelled},
+	config.StatusRejected:   {config.StatusCompleted},
+	config.StatusCompleted:  {},
+	config.StatusCancelled:  {},
+	config.StatusWaitlisted: {config.StatusApproved, config.StatusRejected, config.StatusCancelled},
+}
+
+func AllowedTransitions(status string) []string {
+	allowed, ok := statusTransitions[strings.ToLower(status)]
+	if !ok {
+		return []string{}
+	}
+	return allowed
+}
+
+// CheckTransition returns a conflict error listing the allowed statuses when the application cannot move to the requested status.
+func CheckTransition(from string, to string) error {
+	for _, status := range AllowedTransitions(from) {
+		if status == strings.ToLower(to) {
+			return nil
+		}
+	}
+
+	return ConflictError(CodeInvalidTransition, config.INVALID_STATUS_TRANSITION, map[string]interface{}{
+		"from":    strings.ToLower(from),
+		"to":      strings.ToLower(to),
+		"allowed": AllowedTransitions(from),
+	})
+}
+
+func (ac *Application) GetApplicationStatus(ctx context.Context, db *sql.DB) error {
+	query := `SELECT status FROM applications WHERE id = $1`
+	err := db.QueryRowContext(ctx, query, ac.Id).Scan(&ac.Status)
+	if err == sql.ErrNoRows {
+		return applicationNotFound(ac.Id)
+	}
+	if err != nil {
+		return fmt.Errorf("error getting application status: %v", err)
+	}
+
+	ac.Status = strings.ToLower(ac.Status)
+	return nil
+}
+
+type ApplicationStatusHistory struct {
+	Id             uuid.UUID `json:"id"`
+	ApplicationId  uuid.UUID `json:"application_id"`
+	PreviousStatus string    `json:"previous_status"`
+	NewStatus      string    `json:"new_status"`
+	Actor          string    `json:"actor"`
+	Reason         string    `json:"reason"`
+	CreatedAt      string    `json:"created_at"`
+}
+
+func (h *ApplicationStatusHistory) CreateStatusHistory(ctx context.Context, tx *sql.Tx) error {
+	query := `INSERT INTO application_status_history (application_id, previous_status, new_status, actor, reason) VALUES ($1, $2, $3, $4, $5)`
+	_, err := tx.ExecContext(ctx, query, h.ApplicationId, h.PreviousStatus, h.NewStatus, h.Actor, h.Reason)
+	if err != nil {
+		log.Println("Error inserting application status history:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (ac *Application) GetStatusHistory(ctx context.Context, db *sql.DB) ([]ApplicationStatusHistory, error) {
+	query := `SELECT id, application_id, COALESCE(previous_status, ''), new_status, actor, COALESCE(reason, ''), TO_CHAR(created_at, 'YYYY-MM-DD HH24:MI:SS') FROM application_status_history WHERE application_id = $1 ORDER BY created_at, id`
+
+	rows, err := db.QueryContext(ctx, query, ac.Id)
+	if err != nil {
+		log.Println("Error querying application status history:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []ApplicationStatusHistory{}
+	for rows.Next() {
+		var h ApplicationStatusHistory
+		if err := rows.Scan(&h.Id, &h.ApplicationId, &h.PreviousStatus, &h.NewStatus, &h.Actor, &h.Reason, &h.CreatedAt); err != nil {
+			log.Println("Error scanning application status history row:", err)
+			return nil, err
+		}
+		history = append(history, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return nil, err
+	}
+
+	return history, nil
+}
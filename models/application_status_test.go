@@ -0,0 +1,96 @@
+package models
+
+import (
+	"oneCV/config"
+	"testing"
+)
+
+func TestCheckTransition(t *testing.T) {
+	statuses := []string{
+		config.StatusPending,
+		config.StatusInProgress,
+		config.StatusOnHold,
+		config.StatusApproved,
+		config.StatusRejected,
+		config.StatusCompleted,
+		config.StatusCancelled,
+		config.StatusWaitlisted,
+	}
+
+	// every transition that is allowed, any other pair of statuses is refused
+	allowed := map[[2]string]bool{
+		{config.StatusPending, config.StatusInProgress}:   true,
+		{config.StatusPending, config.StatusOnHold}:       true,
+		{config.StatusPending, config.StatusCancelled}:    true,
+		{config.StatusInProgress, config.StatusApproved}:  true,
+		{config.StatusInProgress, config.StatusRejected}:  true,
+		{config.StatusInProgress, config.StatusOnHold}:    true,
+		{config.StatusInProgress, config.StatusCancelled}: true,
+		{config.StatusOnHold, config.StatusPending}:       true,
+		{config.StatusOnHold, config.StatusInProgress}:    true,
+		{config.StatusOnHold, config.StatusCancelled}:     true,
+		{config.StatusApproved, config.StatusCompleted}:   true,
+		{config.StatusApproved, config.StatusCancelled}:   true,
+		{config.StatusRejected, config.StatusCompleted}:   true,
+		{config.StatusWaitlisted, config.StatusApproved}:  true,
+		{config.StatusWaitlisted, config.StatusRejected}:  true,
+		{config.StatusWaitlisted, config.StatusCancelled}: true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			err := CheckTransition(from, to)
+			if allowed[[2]string{from, to}] {
+				if err != nil {
+					t.Errorf("CheckTransition(%q, %q) = %v, want allowed", from, to, err)
+				}
+				continue
+			}
+
+			e, ok := err.(*Error)
+			if !ok || e.Code != CodeInvalidTransition {
+				t.Errorf("CheckTransition(%q, %q) = %v, want %s", from, to, err, CodeInvalidTransition)
+			}
+		}
+	}
+}
+
+func TestCheckTransitionCase(t *testing.T) {
+	tests := []struct {
+		from    string
+		to      string
+		wantErr bool
+	}{
+		{"Pending", "In Progress", false},
+		{"IN PROGRESS", "approved", false},
+		{"On Hold", "Pending", false},
+		{"Completed", "Pending", true},
+		{"archived", "pending", true},
+	}
+
+	for _, tt := range tests {
+		if err := CheckTransition(tt.from, tt.to); (err != nil) != tt.wantErr {
+			t.Errorf("CheckTransition(%q, %q) error = %v, wantErr %v", tt.from, tt.to, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckTransitionDetails(t *testing.T) {
+	err := CheckTransition("Approved", "Pending")
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("CheckTransition returned %v, want *Error", err)
+	}
+
+	if e.Details["from"] != config.StatusApproved || e.Details["to"] != config.StatusPending {
+		t.Errorf("details = %v", e.Details)
+	}
+	allowed, _ := e.Details["allowed"].([]string)
+	if len(allowed) != 2 || allowed[0] != config.StatusCompleted || allowed[1] != config.StatusCancelled {
+		t.Errorf("allowed = %v, want [%s %s]", e.Details["allowed"], config.StatusCompleted, config.StatusCancelled)
+	}
+
+	if got := AllowedTransitions("archived"); len(got) != 0 {
+		t.Errorf("AllowedTransitions of an unknown status = %v, want none", got)
+	}
+}
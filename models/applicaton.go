@@ -7,22 +7,25 @@ import (
 	"fmt"
 	"log"
 	"oneCV/config"
-	"oneCV/utils"
+	"oneCV/eligibility"
 	"reflect"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type Application struct {
-	Id          uuid.UUID `json:"id"`
-	ApplicantID uuid.UUID `json:"applicant_id"`
-	SchemeID    uuid.UUID `json:"scheme_id"`
-	Status      string    `json:"status"`
-	SubmittedAt time.Time `json:"submitted_at"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	Id              uuid.UUID  `json:"id"`
+	ApplicantID     uuid.UUID  `json:"applicant_id"`
+	SchemeID        uuid.UUID  `json:"scheme_id"`
+	SchemeVersionID *uuid.UUID `json:"scheme_version_id"`
+	Status          string     `json:"status"`
+	SubmittedAt     time.Time  `json:"submitted_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	Version         int        `json:"-"`
 }
 
 type ApplicationRequest struct {
@@ -32,9 +35,12 @@ type ApplicationRequest struct {
 
 type ApplicationUpdateRequest struct {
 	Status string `json:"status"`
+	Actor  string `json:"actor"`
+	Reason string `json:"reason"`
 }
 
 type ApplicationDetail struct {
+	Id            uuid.UUID `json:"id"`
 	ApplicationId uuid.UUID `json:"application_id"`
 	CriteriaId    uuid.UUID `json:"criteria_id"`
 	CriteriaName  string    `json:"criteria_name"`
@@ -45,6 +51,8 @@ type ApplicationDetail struct {
 	BenefitAmount float64   `json:"benefit_amount"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
+
+	BenefitSchedule
 }
 
 type ApplicationResult struct {
@@ -53,6 +61,9 @@ type ApplicationResult struct {
 	Scheme      ApplicationScheme    `json:"scheme"`
 	Status      string               `json:"status"`
 	SubmittedAt string               `json:"submitted_at"`
+	CreatedAt   string               `json:"created_at"`
+	UpdatedAt   string               `json:"updated_at"`
+	Version     int                  `json:"-"`
 }
 
 type ApplicationApplicant struct {
@@ -61,9 +72,13 @@ type ApplicationApplicant struct {
 	EmploymentStatus string    `json:"employment_status"`
 }
 
+// ApplicationScheme is the scheme of an application, VersionId and VersionNumber are the scheme version
+// the application was evaluated against, unset for applications submitted before schemes were versioned.
 type ApplicationScheme struct {
 	Id               uuid.UUID             `json:"id"`
 	Name             string                `json:"name"`
+	VersionId        *uuid.UUID            `json:"version_id,omitempty"`
+	VersionNumber    *int                  `json:"version_number,omitempty"`
 	EligibleCriteria []ApplicationEligible `json:"eligible"`
 }
 
@@ -74,6 +89,10 @@ type ApplicationEligible struct {
 	Benefit       []Benefit              `json:"benefits"`
 }
 
+func applicationNotFound(id uuid.UUID) *Error {
+	return NotFoundError(CodeApplicationNotFound, config.APPLICATION_NOT_FOUND, map[string]interface{}{"id": id})
+}
+
 func (ac *Application) CreateApplication(ctx context.Context, db *sql.DB, req ApplicationRequest) error {
 	applicant := Applicant{Id: req.ApplicantID}
 	if err := applicant.GetApplicantById(ctx, db); err != nil {
@@ -81,27 +100,44 @@ func (ac *Application) CreateApplication(ctx context.Context, db *sql.DB, req Ap
 	}
 
 	scheme := Scheme{Id: req.SchemeID}
-	criteria, err := scheme.GetSchemeCriteria(ctx, db)
+	if err := scheme.GetSchemeById(ctx, db); err != nil {
+		return err
+	}
+
+	ac.ApplicantID = applicant.Id
+	ac.SchemeID = scheme.Id
+	ac.Status = config.StatusPending
+	ac.SubmittedAt = time.Now()
+
+	if !scheme.AcceptsApplicationsOn(ac.SubmittedAt) {
+		from, to := scheme.ApplicationWindow()
+		return ConflictError(CodeApplicationsClosed, config.SCHEME_APPLICATIONS_CLOSED, map[string]interface{}{"scheme_id": scheme.Id, "application_from": from, "application_to": to})
+	}
+
+	if err := ac.CheckDuplicate(ctx, db, scheme.ApplicationPolicy); err != nil {
+		return err
+	}
 
+	if err := ac.checkRelationships(ctx, db); err != nil {
+		return err
+	}
+
+	// eligibility is decided as of the submission date
+	evaluator, err := NewEvaluator(ctx, db)
 	if err != nil {
 		return err
 	}
 
-	eligibleCriteria, err := ac.CheckEligibility(applicant, criteria)
+	eligibleCriteria, err := ac.CheckEligibility(evaluator.WithDate(ac.SubmittedAt), applicant, scheme)
 	if err != nil {
 		return err
 	}
 
 	if len(eligibleCriteria) == 0 {
-		return fmt.Errorf("this scheme is not eligible for this applicant")
+		return ValidationError(CodeNotEligible, config.APPLICANT_NOT_ELIGIBLE)
 	}
 
-	ac.ApplicantID = applicant.Id
-	ac.SchemeID = scheme.Id
-	ac.Status = config.StatusPending
-	ac.SubmittedAt = time.Now()
-
-	err = ac.SaveApplication(ctx, db, scheme.Id, eligibleCriteria)
+	err = ac.SaveApplication(ctx, db, scheme, eligibleCriteria)
 	if err != nil {
 		return err
 	}
@@ -109,81 +145,19 @@ func (ac *Application) CreateApplication(ctx context.Context, db *sql.DB, req Ap
 	return nil
 }
 
-func (ac *Application) CheckEligibility(applicant Applicant, criteria []Criteria) ([]Criteria, error) {
-	eligibleCriteria := []Criteria{}
-	for _, v := range criteria {
-		if len(v.CriteriaValue) > 0 {
-			isJson := utils.IsJson(v.CriteriaValue)
-			if !isJson {
-				v.CriteriaValue = strings.ReplaceAll(v.CriteriaValue, `"`, "")
-			}
-
-			if isJson {
-				var criteriaValue map[string]interface{}
-				err := json.Unmarshal([]byte(v.CriteriaValue), &criteriaValue)
-				if err != nil {
-					log.Printf("Error unmarshaling criteria value for criteria %d: %v", v.Id, err)
-					return eligibleCriteria, err
-				}
-
-				// check has_children
-				if criteriaValue["school_level"] != nil {
-					for _, member := range applicant.HouseholdMembers {
-						age, err := utils.CalculateAge(*member.DateOfBirth)
-						if err != nil {
-							log.Printf("Parse applicant household member age error %+v", err)
-							return eligibleCriteria, err
-						}
-
-						switch criteriaValue["school_level"] {
-						case "== primary":
-							if age >= 6 && age <= 12 {
-								eligibleCriteria = append(eligibleCriteria, v)
-							}
-						case "== secondary":
-							if age >= 13 && age <= 18 {
-								eligibleCriteria = append(eligibleCriteria, v)
-							}
-						default:
-							log.Printf("Applicant child not eligible %+v %+v", criteriaValue["school_level"], age)
-							return eligibleCriteria, err
-						}
-					}
-				}
-			} else {
-				switch v.CriteriaKey {
-				case "employment_status":
-					if v.CriteriaValue == applicant.EmploymentStatus {
-						eligibleCriteria = append(eligibleCriteria, v)
-					}
-				case "marital_status":
-					if v.CriteriaValue == applicant.MaritalStatus {
-						eligibleCriteria = append(eligibleCriteria, v)
-					}
-				case "sex":
-					if v.CriteriaValue == applicant.Sex {
-						eligibleCriteria = append(eligibleCriteria, v)
-					}
-				}
-			}
-		}
+func (ac *Application) CheckEligibility(evaluator eligibility.Evaluator, applicant Applicant, scheme Scheme) ([]SchemeCriteria, error) {
+	eligibleCriteria, err := scheme.EligibleCriteria(evaluator, applicant.Profile())
+	if err != nil {
+		log.Printf("Error checking eligibility of applicant %s for scheme %s: %v", applicant.Id, scheme.Id, err)
+		return eligibleCriteria, err
 	}
 
 	return eligibleCriteria, nil
 }
 
-func (ac *Application) SaveApplication(ctx context.Context, db *sql.DB, schemUUID uuid.UUID, criteria []Criteria) error {
-	criteriaIds := []uuid.UUID{}
-	for _, v := range criteria {
-		criteriaIds = append(criteriaIds, v.Id)
-	}
-
-	scheme := Scheme{}
-	benefits, err := scheme.GetBenefitsByCriteriaIds(ctx, db, criteriaIds)
-	if err != nil {
-		return err
-	}
-
+// SaveApplication stores the application with the criteria it is eligible for, bound to the scheme
+// version those criteria were read from.
+func (ac *Application) SaveApplication(ctx context.Context, db *sql.DB, scheme Scheme, criteria []SchemeCriteria) error {
 	// create application
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
@@ -192,34 +166,53 @@ func (ac *Application) SaveApplication(ctx context.Context, db *sql.DB, schemUUI
 	}
 	defer tx.Rollback()
 
-	query := `INSERT INTO applications (applicant_id, scheme_id, status, submitted_at) VALUES ($1, $2, $3, $4) RETURNING (id)`
+	ac.SchemeVersionID = scheme.VersionId
+	if ac.SchemeVersionID == nil {
+		versionId, err := scheme.publishedVersion(ctx, tx)
+		if err != nil {
+			return err
+		}
+		ac.SchemeVersionID = &versionId
+	}
+
+	// the policy is read again under a lock so a concurrent policy change cannot key the application by the old one
+	if err := tx.QueryRowContext(ctx, `SELECT application_policy FROM schemes WHERE id = $1 FOR SHARE`, ac.SchemeID).Scan(&scheme.ApplicationPolicy); err != nil {
+		log.Println("Error locking scheme:", err)
+		return err
+	}
+
+	query := `INSERT INTO applications (applicant_id, scheme_id, scheme_version_id, status, submitted_at, dedupe_key) VALUES ($1, $2, $3, $4, $5, $6) RETURNING (id)`
 
 	var applicationId uuid.UUID
-	err = tx.QueryRowContext(ctx, query, ac.ApplicantID, ac.SchemeID, ac.Status, ac.SubmittedAt).Scan(&applicationId)
+	err = tx.QueryRowContext(ctx, query, ac.ApplicantID, ac.SchemeID, ac.SchemeVersionID, ac.Status, ac.SubmittedAt, dedupeKey(scheme.ApplicationPolicy, ac.Status, ac.SubmittedAt)).Scan(&applicationId)
 	if err != nil {
+		// another submission won the race, report the application it created
+		if isDedupeViolation(err) {
+			tx.Rollback()
+			return ac.CheckDuplicate(ctx, db, scheme.ApplicationPolicy)
+		}
 		log.Println("Error inserting application:", err)
 		return err
 	}
+	ac.Id = applicationId
 
-	for _, b := range benefits {
-		ad := ApplicationDetail{ApplicationId: applicationId, BenefitId: b.Id, BenefitName: *b.Name, BenefitAmount: *b.Amount}
-
-		for _, c := range criteria {
-			if c.Id == b.CriteriaId {
-				ad.CriteriaId = c.Id
-				ad.CriteriaKey = c.CriteriaKey
-				ad.CriteriaValue = c.CriteriaValue
-
-				break
-			}
+	for _, c := range criteria {
+		criteriaValue, err := json.Marshal(c.Conditions)
+		if err != nil {
+			return fmt.Errorf("marshal criteria value failed: %v", err)
 		}
 
-		query := `INSERT INTO application_details (application_id, criteria_id, criteria_name, criteria_key, criteria_value, benefit_id, benefit_name, benefit_amount) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+		for _, b := range c.Benefits {
+			// the schedule is captured with the amount so later changes to the benefit do not alter it
+			ad := ApplicationDetail{ApplicationId: applicationId, CriteriaId: c.Id, CriteriaKey: eligibility.RuleKey, CriteriaValue: string(criteriaValue), BenefitId: b.Id, BenefitName: *b.Name, BenefitAmount: *b.Amount, BenefitSchedule: b.Normalized()}
 
-		_, err = tx.ExecContext(ctx, query, ad.ApplicationId, ad.CriteriaId, ad.CriteriaName, ad.CriteriaKey, ad.CriteriaValue, ad.BenefitId, ad.BenefitName, ad.BenefitAmount)
-		if err != nil {
-			log.Println("Error inserting application detail:", err)
-			return err
+			query := `INSERT INTO application_details (application_id, criteria_id, criteria_name, criteria_key, criteria_value, benefit_id, benefit_name, benefit_amount, frequency, instalments, start_rule, start_date) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+			_, err = tx.ExecContext(ctx, query, ad.ApplicationId, ad.CriteriaId, ad.CriteriaName, ad.CriteriaKey, ad.CriteriaValue, ad.BenefitId, ad.BenefitName, ad.BenefitAmount, ad.Frequency, ad.Instalments, ad.StartRule, ad.StartDate)
+			if err != nil {
+				log.Println("Error inserting application detail:", err)
+				return err
+			}
 		}
 	}
 
@@ -231,29 +224,107 @@ func (ac *Application) SaveApplication(ctx context.Context, db *sql.DB, schemUUI
 	return nil
 }
 
-func (ac *Application) GetAllApplications(ctx context.Context, db *sql.DB) ([]ApplicationResult, error) {
-	query := `SELECT a.id AS a_id, app.id AS app_id, app.name AS app_name, app.employment_status, s.id AS s_id, s.name AS s_name, ad.criteria_key, ad.criteria_value, ad.benefit_id, b.name AS benefit_name, b.amount, a.status, TO_CHAR(a.submitted_at, 'YYYY-MM-DD HH24:MI:SS') as submitted_at FROM applications a INNER JOIN applicants app ON a.applicant_id = app.id INNER JOIN schemes s ON a.scheme_id = s.id LEFT JOIN application_details ad ON ad.application_id = a.id LEFT JOIN benefits b ON ad.benefit_id = b.id`
+var applicationSortFields = map[string]SortField{
+	"submitted_at": {Column: "a.submitted_at", Cast: "timestamp"},
+	"created_at":   {Column: "a.created_at", Cast: "timestamp"},
+	"updated_at":   {Column: "a.updated_at", Cast: "timestamp"},
+}
+
+// ApplicationFilter narrows the application list, empty fields are ignored.
+// SubmittedFrom and SubmittedTo are inclusive dates in YYYY-MM-DD.
+type ApplicationFilter struct {
+	Status        string
+	SchemeId      *uuid.UUID
+	ApplicantId   *uuid.UUID
+	SubmittedFrom string
+	SubmittedTo   string
+}
+
+func (f ApplicationFilter) Apply(q *ListQuery) {
+	if f.Status != "" {
+		q.Where("a.status = ?", strings.ToLower(f.Status))
+	}
+	if f.SchemeId != nil {
+		q.Where("a.scheme_id = ?", *f.SchemeId)
+	}
+	if f.ApplicantId != nil {
+		q.Where("a.applicant_id = ?", *f.ApplicantId)
+	}
+	if f.SubmittedFrom != "" {
+		q.Where("a.submitted_at >= ?::date", f.SubmittedFrom)
+	}
+	if f.SubmittedTo != "" {
+		q.Where("a.submitted_at < ?::date + INTERVAL '1 day'", f.SubmittedTo)
+	}
+}
 
-	rows, err := db.QueryContext(ctx, query)
+func (ac *Application) GetAllApplications(ctx context.Context, db *sql.DB, q ListQuery) ([]ApplicationResult, Page, error) {
+	from := `FROM applications a WHERE true`
+	page := Page{Limit: q.Limit}
+
+	ids, nextCursor, err := q.fetchPage(ctx, db, from, "a.id", applicationSortFields, "submitted_at")
+	if err != nil {
+		return nil, page, err
+	}
+	page.NextCursor = nextCursor
+
+	page.Total, err = q.count(ctx, db, from)
+	if err != nil {
+		return nil, page, err
+	}
+
+	applications, err := ac.FetchApplications(ctx, db, ` WHERE a.id = ANY($1)`, pq.Array(idStrings(ids)))
+	if err != nil {
+		return nil, page, err
+	}
+
+	return orderByIds(applications, ids, func(a ApplicationResult) uuid.UUID { return a.Id }), page, nil
+}
+
+func (ac *Application) GetApplicationById(ctx context.Context, db *sql.DB) (ApplicationResult, error) {
+	whereClause := ` WHERE a.id = $1`
+	applications, err := ac.FetchApplications(ctx, db, whereClause, ac.Id)
+	if err != nil {
+		return ApplicationResult{}, err
+	}
+
+	if len(applications) == 0 {
+		return ApplicationResult{}, applicationNotFound(ac.Id)
+	}
+
+	return applications[0], nil
+}
+
+// FetchApplications groups the application rows with the criteria and benefits captured in application_details at submission.
+func (ac *Application) FetchApplications(ctx context.Context, db *sql.DB, whereClause string, args ...interface{}) ([]ApplicationResult, error) {
+	query := `SELECT a.id AS a_id, a.version, app.id AS app_id, app.name AS app_name, app.employment_status, s.id AS s_id, s.name AS s_name, sv.id AS sv_id, sv.number, ad.criteria_key, ad.criteria_value, ad.benefit_id, ad.benefit_name, ad.benefit_amount, ad.frequency, ad.instalments, ad.start_rule, TO_CHAR(ad.start_date, 'YYYY-MM-DD'), a.status, TO_CHAR(a.submitted_at, 'YYYY-MM-DD HH24:MI:SS') as submitted_at, TO_CHAR(a.created_at, 'YYYY-MM-DD HH24:MI:SS') as created_at, TO_CHAR(a.updated_at, 'YYYY-MM-DD HH24:MI:SS') as updated_at FROM applications a INNER JOIN applicants app ON a.applicant_id = app.id INNER JOIN schemes s ON a.scheme_id = s.id LEFT JOIN scheme_versions sv ON a.scheme_version_id = sv.id LEFT JOIN application_details ad ON ad.application_id = a.id` + whereClause + ` ORDER BY a.submitted_at, a.id, ad.created_at`
+
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %v", err)
 	}
 	defer rows.Close()
 
 	applicationMap := make(map[uuid.UUID]*ApplicationResult)
+	order := []uuid.UUID{}
 
 	for rows.Next() {
 		var id uuid.UUID
+		var version int
 		var applicant ApplicationApplicant
 		var scheme ApplicationScheme
 		var status string
-		var submittedAt string
-		var criteriaKey, criteriaValue string
+		var submittedAt, createdAt, updatedAt string
+		var nullCriteriaKey, nullCriteriaValue sql.NullString
 		var benefit Benefit
+		var frequency, startRule sql.NullString
+		var instalments sql.NullInt64
 
-		if err := rows.Scan(&id, &applicant.Id, &applicant.Name, &applicant.EmploymentStatus, &scheme.Id, &scheme.Name, &criteriaKey, &criteriaValue, &benefit.Id, &benefit.Name, &benefit.Amount, &status, &submittedAt); err != nil {
+		if err := rows.Scan(&id, &version, &applicant.Id, &applicant.Name, &applicant.EmploymentStatus, &scheme.Id, &scheme.Name, &scheme.VersionId, &scheme.VersionNumber, &nullCriteriaKey, &nullCriteriaValue, &benefit.Id, &benefit.Name, &benefit.Amount, &frequency, &instalments, &startRule, &benefit.StartDate, &status, &submittedAt, &createdAt, &updatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %v", err)
 		}
+		criteriaKey, criteriaValue := nullCriteriaKey.String, nullCriteriaValue.String
+		benefit.Frequency, benefit.Instalments, benefit.StartRule = frequency.String, int(instalments.Int64), startRule.String
 
 		application, exists := applicationMap[id]
 		if !exists {
@@ -267,12 +338,18 @@ func (ac *Application) GetAllApplications(ctx context.Context, db *sql.DB) ([]Ap
 				Scheme: ApplicationScheme{
 					Id:               scheme.Id,
 					Name:             scheme.Name,
+					VersionId:        scheme.VersionId,
+					VersionNumber:    scheme.VersionNumber,
 					EligibleCriteria: []ApplicationEligible{},
 				},
 				Status:      status,
 				SubmittedAt: submittedAt,
+				CreatedAt:   createdAt,
+				UpdatedAt:   updatedAt,
+				Version:     version,
 			}
 			applicationMap[id] = application
+			order = append(order, id)
 		}
 
 		if criteriaKey != "" && criteriaValue != "" {
@@ -282,8 +359,14 @@ func (ac *Application) GetAllApplications(ctx context.Context, db *sql.DB) ([]Ap
 				parsedCriteria = criteriaValue
 			}
 
+			// rule criteria are already a conditions object
+			criteria := map[string]interface{}{criteriaKey: parsedCriteria}
+			if rule, ok := parsedCriteria.(map[string]interface{}); ok && criteriaKey == eligibility.RuleKey {
+				criteria = rule
+			}
+
 			for i, eligible := range application.Scheme.EligibleCriteria {
-				if val, ok := eligible.Criteria[criteriaKey]; ok && reflect.DeepEqual(val, parsedCriteria) {
+				if reflect.DeepEqual(eligible.Criteria, criteria) {
 					application.Scheme.EligibleCriteria[i].Benefit = append(application.Scheme.EligibleCriteria[i].Benefit, benefit)
 					found = true
 					break
@@ -291,18 +374,20 @@ func (ac *Application) GetAllApplications(ctx context.Context, db *sql.DB) ([]Ap
 			}
 			if !found {
 				application.Scheme.EligibleCriteria = append(application.Scheme.EligibleCriteria, ApplicationEligible{
-					Criteria: map[string]interface{}{
-						criteriaKey: parsedCriteria,
-					},
-					Benefit: []Benefit{benefit},
+					Criteria: criteria,
+					Benefit:  []Benefit{benefit},
 				})
 			}
 		}
 	}
 
-	var results []ApplicationResult
-	for _, application := range applicationMap {
-		results = append(results, *application)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %v", err)
+	}
+
+	results := []ApplicationResult{}
+	for _, id := range order {
+		results = append(results, *applicationMap[id])
 	}
 
 	return results, nil
@@ -316,7 +401,7 @@ func (ac *Application) CheckApplicationExist(ctx context.Context, db *sql.DB) er
 		return fmt.Errorf("error checking application existence: %v", err)
 	}
 	if !exists {
-		return fmt.Errorf("application %s does not exist", ac.Id)
+		return applicationNotFound(ac.Id)
 	}
 
 	return nil
@@ -330,6 +415,24 @@ func (ac *Application) DeleteApplication(ctx context.Context, db *sql.DB) error
 	}
 	defer tx.Rollback()
 
+	// payouts are financial records, an application with any is kept
+	var payouts bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM ledger_entries WHERE application_id = $1)`, ac.Id).Scan(&payouts)
+	if err != nil {
+		log.Println("Error checking application payouts:", err)
+		return err
+	}
+	if payouts {
+		return ConflictError(CodeApplicationHasPayouts, config.APPLICATION_HAS_PAYOUTS, map[string]interface{}{"id": ac.Id})
+	}
+
+	dQuery := `DELETE FROM disbursements WHERE application_id = $1`
+	_, err = tx.ExecContext(ctx, dQuery, ac.Id)
+	if err != nil {
+		log.Println("Error delete disbursements:", err)
+		return err
+	}
+
 	adQuery := `DELETE FROM application_details WHERE application_id = $1`
 	_, err = tx.ExecContext(ctx, adQuery, ac.Id)
 	if err != nil {
@@ -337,8 +440,15 @@ func (ac *Application) DeleteApplication(ctx context.Context, db *sql.DB) error
 		return err
 	}
 
-	aQuery := `DELETE FROM applications WHERE id = $1`
-	result, err := tx.ExecContext(ctx, aQuery, ac.Id)
+	hQuery := `DELETE FROM application_status_history WHERE application_id = $1`
+	_, err = tx.ExecContext(ctx, hQuery, ac.Id)
+	if err != nil {
+		log.Println("Error delete application status history:", err)
+		return err
+	}
+
+	aQuery := `DELETE FROM applications WHERE id = $1 AND ($2 = 0 OR version = $2)`
+	result, err := tx.ExecContext(ctx, aQuery, ac.Id, ac.Version)
 	if err != nil {
 		log.Println("Error delete application:", err)
 		return err
@@ -349,7 +459,7 @@ func (ac *Application) DeleteApplication(ctx context.Context, db *sql.DB) error
 		return fmt.Errorf("error checking rows affected: %v", err)
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("no application found with ID %s or application already deleted", ac.Id)
+		return missedWrite(ac.Id, ac.Version, applicationNotFound(ac.Id))
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -368,10 +478,74 @@ func (ac *Application) UpdateApplication(ctx context.Context, db *sql.DB, req Ap
 	}
 	defer tx.Rollback()
 
-	query := `UPDATE applications SET status = $1, updated_at = $2 WHERE id = $3`
-	_, err = tx.ExecContext(ctx, query, req.Status, time.Now(), ac.Id)
+	// lock the application so concurrent updates see the latest status
+	var status, policy string
+	var submittedAt time.Time
+	var version int
+	var committed *float64
+	err = tx.QueryRowContext(ctx, `SELECT a.scheme_id, a.applicant_id, a.status, s.application_policy, a.submitted_at, a.committed_amount, a.version FROM applications a INNER JOIN schemes s ON a.scheme_id = s.id WHERE a.id = $1 FOR UPDATE OF a`, ac.Id).Scan(&ac.SchemeID, &ac.ApplicantID, &status, &policy, &submittedAt, &committed, &version)
 	if err != nil {
-		log.Println("Error updating applicant:", err)
+		log.Println("Error getting application status:", err)
+		return err
+	}
+
+	if err := CheckVersion(ac.Id, version, ac.Version); err != nil {
+		return err
+	}
+
+	if err := CheckTransition(status, req.Status); err != nil {
+		return err
+	}
+
+	// an approval commits the benefits of the application against the scheme funding until it is cancelled
+	ac.Status = strings.ToLower(req.Status)
+	switch ac.Status {
+	case config.StatusApproved:
+		// the applicant is locked so approvals of schemes related to each other are checked one at a time
+		if _, err := tx.ExecContext(ctx, `SELECT id FROM applicants WHERE id = $1 FOR UPDATE`, ac.ApplicantID); err != nil {
+			log.Println("Error locking applicant:", err)
+			return err
+		}
+		if err := ac.checkRelationships(ctx, tx); err != nil {
+			return err
+		}
+
+		claim, err := ac.claimFunding(ctx, tx, ac.SchemeID, ac.ApplicantID)
+		if err != nil {
+			return err
+		}
+		if claim.fits() {
+			committed = &claim.Amount
+		} else if claim.FundingPolicy() == config.BudgetWaitlist && strings.ToLower(status) != config.StatusWaitlisted {
+			ac.Status = config.StatusWaitlisted
+		} else {
+			return claim.exceeded()
+		}
+	case config.StatusCancelled:
+		committed = nil
+	}
+
+	// closed applications release their dedupe key so the applicant can apply again
+	query := `UPDATE applications SET status = $1, dedupe_key = CASE WHEN $2::VARCHAR IS NULL THEN NULL ELSE dedupe_key END, committed_amount = $3, updated_at = $4, version = version + 1 WHERE id = $5 RETURNING version`
+	err = tx.QueryRowContext(ctx, query, ac.Status, dedupeKey(policy, ac.Status, submittedAt), committed, time.Now(), ac.Id).Scan(&ac.Version)
+	if err != nil {
+		log.Println("Error updating application:", err)
+		return err
+	}
+
+	// approved benefits are scheduled for payment, cancelling the application stops what is left unpaid
+	switch ac.Status {
+	case config.StatusApproved:
+		err = ac.scheduleDisbursements(ctx, tx, time.Now())
+	case config.StatusCancelled:
+		_, err = ac.applyDisbursementAction(ctx, tx, CancelDisbursements)
+	}
+	if err != nil {
+		return err
+	}
+
+	history := ApplicationStatusHistory{ApplicationId: ac.Id, PreviousStatus: strings.ToLower(status), NewStatus: ac.Status, Actor: req.Actor, Reason: req.Reason}
+	if err := history.CreateStatusHistory(ctx, tx); err != nil {
 		return err
 	}
 
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"oneCV/config"
+	"strings"
+	"time"
+)
+
+// BenefitSchedule is how a benefit is paid once an application is approved: its amount is paid every
+// frequency period, Instalments times, from the date given by StartRule. A one-off benefit is paid once.
+type BenefitSchedule struct {
+	Frequency   string  `json:"frequency"`
+	Instalments int     `json:"instalments"`
+	StartRule   string  `json:"start_rule"`
+	StartDate   *string `json:"start_date,omitempty"`
+}
+
+// months between instalments of each frequency
+var frequencyMonths = map[string]int{
+	config.FrequencyOneOff:    0,
+	config.FrequencyMonthly:   1,
+	config.FrequencyQuarterly: 3,
+	config.FrequencyYearly:    12,
+}
+
+// Normalized fills in the defaults of the schedule, a single instalment paid on approval.
+func (s BenefitSchedule) Normalized() BenefitSchedule {
+	s.Frequency, s.StartRule = strings.ToLower(s.Frequency), strings.ToLower(s.StartRule)
+	if s.Frequency == "" {
+		s.Frequency = config.FrequencyOneOff
+	}
+	if s.Instalments <= 0 || s.Frequency == config.FrequencyOneOff {
+		s.Instalments = 1
+	}
+	if s.StartRule == "" {
+		s.StartRule = config.StartOnApproval
+	}
+	if s.StartRule != config.StartFixedDate {
+		s.StartDate = nil
+	}
+	return s
+}
+
+// DueDates returns the due date of each instalment of a benefit approved on the date. Instalments of a
+// fixed start date that fell due before the approval are due on the approval date.
+func (s BenefitSchedule) DueDates(approvedOn time.Time) []time.Time {
+	s = s.Normalized()
+	approvedOn = time.Date(approvedOn.Year(), approvedOn.Month(), approvedOn.Day(), 0, 0, 0, 0, time.UTC)
+
+	start := approvedOn
+	switch s.StartRule {
+	case config.StartNextMonth:
+		start = time.Date(approvedOn.Year(), approvedOn.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	case config.StartFixedDate:
+		if s.StartDate != nil {
+			if date, err := time.Parse("2006-01-02", *s.StartDate); err == nil {
+				start = date
+			}
+		}
+	}
+
+	dates := make([]time.Time, 0, s.Instalments)
+	for i := 0; i < s.Instalments; i++ {
+		due := addMonths(start, i*frequencyMonths[s.Frequency])
+		if due.Before(approvedOn) {
+			due = approvedOn
+		}
+		dates = append(dates, due)
+	}
+	return dates
+}
+
+// addMonths moves the date by whole months, keeping the day or the last day of shorter months.
+func addMonths(date time.Time, months int) time.Time {
+	first := time.Date(date.Year(), date.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := date.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
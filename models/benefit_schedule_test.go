@@ -0,0 +1,161 @@
+package models
+
+import (
+	"oneCV/config"
+	"testing"
+	"time"
+)
+
+func stringPtr(v string) *string {
+	return &v
+}
+
+func TestBenefitScheduleNormalized(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule BenefitSchedule
+		want     BenefitSchedule
+	}{
+		{
+			"defaults",
+			BenefitSchedule{},
+			BenefitSchedule{Frequency: config.FrequencyOneOff, Instalments: 1, StartRule: config.StartOnApproval},
+		},
+		{
+			"one-off is paid once",
+			BenefitSchedule{Frequency: config.FrequencyOneOff, Instalments: 6},
+			BenefitSchedule{Frequency: config.FrequencyOneOff, Instalments: 1, StartRule: config.StartOnApproval},
+		},
+		{
+			"missing instalments",
+			BenefitSchedule{Frequency: config.FrequencyMonthly, Instalments: 0},
+			BenefitSchedule{Frequency: config.FrequencyMonthly, Instalments: 1, StartRule: config.StartOnApproval},
+		},
+		{
+			"lowercased",
+			BenefitSchedule{Frequency: "Quarterly", Instalments: 4, StartRule: "Next_Month"},
+			BenefitSchedule{Frequency: config.FrequencyQuarterly, Instalments: 4, StartRule: config.StartNextMonth},
+		},
+		{
+			"start date dropped without a fixed date start",
+			BenefitSchedule{Frequency: config.FrequencyMonthly, Instalments: 3, StartRule: config.StartNextMonth, StartDate: stringPtr("2024-03-01")},
+			BenefitSchedule{Frequency: config.FrequencyMonthly, Instalments: 3, StartRule: config.StartNextMonth},
+		},
+		{
+			"start date kept with a fixed date start",
+			BenefitSchedule{Frequency: config.FrequencyMonthly, Instalments: 3, StartRule: config.StartFixedDate, StartDate: stringPtr("2024-03-01")},
+			BenefitSchedule{Frequency: config.FrequencyMonthly, Instalments: 3, StartRule: config.StartFixedDate, StartDate: stringPtr("2024-03-01")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.schedule.Normalized()
+			if got.Frequency != tt.want.Frequency || got.Instalments != tt.want.Instalments || got.StartRule != tt.want.StartRule {
+				t.Errorf("Normalized() = %+v, want %+v", got, tt.want)
+			}
+			if (got.StartDate == nil) != (tt.want.StartDate == nil) || (got.StartDate != nil && *got.StartDate != *tt.want.StartDate) {
+				t.Errorf("Normalized() start date = %v, want %v", got.StartDate, tt.want.StartDate)
+			}
+		})
+	}
+}
+
+func TestBenefitScheduleDueDates(t *testing.T) {
+	tests := []struct {
+		name       string
+		schedule   BenefitSchedule
+		approvedOn string
+		want       []string
+	}{
+		{
+			"one-off on approval",
+			BenefitSchedule{},
+			"2024-01-31",
+			[]string{"2024-01-31"},
+		},
+		{
+			"monthly from a month end",
+			BenefitSchedule{Frequency: config.FrequencyMonthly, Instalments: 4},
+			"2024-01-31",
+			[]string{"2024-01-31", "2024-02-29", "2024-03-31", "2024-04-30"},
+		},
+		{
+			"monthly across a year end",
+			BenefitSchedule{Frequency: config.FrequencyMonthly, Instalments: 3},
+			"2024-11-15",
+			[]string{"2024-11-15", "2024-12-15", "2025-01-15"},
+		},
+		{
+			"quarterly from a month end",
+			BenefitSchedule{Frequency: config.FrequencyQuarterly, Instalments: 4},
+			"2024-11-30",
+			[]string{"2024-11-30", "2025-02-28", "2025-05-30", "2025-08-30"},
+		},
+		{
+			"yearly from a leap day",
+			BenefitSchedule{Frequency: config.FrequencyYearly, Instalments: 3},
+			"2024-02-29",
+			[]string{"2024-02-29", "2025-02-28", "2026-02-28"},
+		},
+		{
+			"next month",
+			BenefitSchedule{Frequency: config.FrequencyMonthly, Instalments: 2, StartRule: config.StartNextMonth},
+			"2024-12-31",
+			[]string{"2025-01-01", "2025-02-01"},
+		},
+		{
+			"fixed date after the approval",
+			BenefitSchedule{Frequency: config.FrequencyMonthly, Instalments: 2, StartRule: config.StartFixedDate, StartDate: stringPtr("2024-03-31")},
+			"2024-01-10",
+			[]string{"2024-03-31", "2024-04-30"},
+		},
+		{
+			"fixed date before the approval",
+			BenefitSchedule{Frequency: config.FrequencyMonthly, Instalments: 3, StartRule: config.StartFixedDate, StartDate: stringPtr("2024-01-15")},
+			"2024-02-20",
+			[]string{"2024-02-20", "2024-02-20", "2024-03-15"},
+		},
+		{
+			"fixed start without a date",
+			BenefitSchedule{Frequency: config.FrequencyOneOff, StartRule: config.StartFixedDate},
+			"2024-05-05",
+			[]string{"2024-05-05"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approvedOn, err := time.Parse("2006-01-02", tt.approvedOn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := tt.schedule.DueDates(approvedOn)
+			if len(got) != len(tt.want) {
+				t.Fatalf("DueDates(%s) = %v, want %v", tt.approvedOn, got, tt.want)
+			}
+			for i, date := range got {
+				if date.Format("2006-01-02") != tt.want[i] {
+					t.Errorf("DueDates(%s)[%d] = %s, want %s", tt.approvedOn, i, date.Format("2006-01-02"), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// the time and zone of the approval do not move the due dates
+func TestBenefitScheduleDueDatesIgnoresTime(t *testing.T) {
+	approvedOn := time.Date(2024, time.January, 31, 23, 30, 0, 0, time.FixedZone("SGT", 8*60*60))
+	got := BenefitSchedule{Frequency: config.FrequencyMonthly, Instalments: 2}.DueDates(approvedOn)
+
+	want := []time.Time{
+		time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("DueDates[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
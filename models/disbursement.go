@@ -0,0 +1,183 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"oneCV/config"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Disbursement is one instalment of a benefit of an approved application, scheduled from the benefit
+// schedule captured in the application details when it was submitted.
+type Disbursement struct {
+	Id                  uuid.UUID  `json:"id"`
+	ApplicationId       uuid.UUID  `json:"application_id"`
+	ApplicationDetailId uuid.UUID  `json:"application_detail_id"`
+	BenefitId           *uuid.UUID `json:"benefit_id"`
+	BenefitName         string     `json:"benefit_name"`
+	Instalment          int        `json:"instalment"`
+	Amount              float64    `json:"amount"`
+	DueDate             string     `json:"due_date"`
+	Status              string     `json:"status"`
+	CreatedAt           string     `json:"created_at"`
+	UpdatedAt           string     `json:"updated_at"`
+}
+
+// DisbursementAction changes the instalments of a schedule in one of the From statuses to To.
+type DisbursementAction struct {
+	From []string
+	To   string
+}
+
+var (
+	PauseDisbursements  = DisbursementAction{From: []string{config.DisbursementScheduled}, To: config.DisbursementPaused}
+	ResumeDisbursements = DisbursementAction{From: []string{config.DisbursementPaused}, To: config.DisbursementScheduled}
+	// Unpaid disbursements are cancelled. Payouts that failed or were reversed are included on purpose: the
+	// ledger pays them out again otherwise, and cancelling is what stops that retry once an application or
+	// its remaining payments are called off.
+	CancelDisbursements = DisbursementAction{From: []string{config.DisbursementScheduled, config.DisbursementPaused, config.DisbursementFailed, config.DisbursementReversed}, To: config.DisbursementCancelled}
+)
+
+// GetDisbursements returns the disbursement schedule of the application by due date.
+func (ac *Application) GetDisbursements(ctx context.Context, db *sql.DB) ([]Disbursement, error) {
+	query := `SELECT id, application_id, application_detail_id, benefit_id, benefit_name, instalment, amount, TO_CHAR(due_date, 'YYYY-MM-DD'), status, TO_CHAR(created_at, 'YYYY-MM-DD HH24:MI:SS'), TO_CHAR(updated_at, 'YYYY-MM-DD HH24:MI:SS') FROM disbursements WHERE application_id = $1 ORDER BY due_date, benefit_name, instalment`
+
+	rows, err := db.QueryContext(ctx, query, ac.Id)
+	if err != nil {
+		log.Println("Error querying disbursements:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	disbursements := []Disbursement{}
+	for rows.Next() {
+		var d Disbursement
+		if err := rows.Scan(&d.Id, &d.ApplicationId, &d.ApplicationDetailId, &d.BenefitId, &d.BenefitName, &d.Instalment, &d.Amount, &d.DueDate, &d.Status, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			log.Println("Error scanning disbursement row:", err)
+			return nil, err
+		}
+		disbursements = append(disbursements, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return nil, err
+	}
+
+	return disbursements, nil
+}
+
+// UpdateDisbursements applies the action to the schedule of the application and returns how many
+// instalments it changed. Instalments in other statuses are left as they are. The application version
+// is checked against ac.Version and bumped when an instalment changed.
+func (ac *Application) UpdateDisbursements(ctx context.Context, db *sql.DB, action DisbursementAction) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// lock the application so the schedule does not change under a concurrent status update
+	var version int
+	err = tx.QueryRowContext(ctx, `SELECT version FROM applications WHERE id = $1 FOR UPDATE`, ac.Id).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, applicationNotFound(ac.Id)
+	}
+	if err != nil {
+		log.Println("Error locking application:", err)
+		return 0, err
+	}
+
+	if err := CheckVersion(ac.Id, version, ac.Version); err != nil {
+		return 0, err
+	}
+	ac.Version = version
+
+	changed, err := ac.applyDisbursementAction(ctx, tx, action)
+	if err != nil {
+		return 0, err
+	}
+
+	if changed > 0 {
+		query := `UPDATE applications SET updated_at = $1, version = version + 1 WHERE id = $2 RETURNING version`
+		if err := tx.QueryRowContext(ctx, query, time.Now(), ac.Id).Scan(&ac.Version); err != nil {
+			log.Println("Error updating application version:", err)
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return changed, nil
+}
+
+func (ac *Application) applyDisbursementAction(ctx context.Context, tx *sql.Tx, action DisbursementAction) (int64, error) {
+	query := `UPDATE disbursements SET status = $1, updated_at = $2 WHERE application_id = $3 AND status = ANY($4)`
+	result, err := tx.ExecContext(ctx, query, action.To, time.Now(), ac.Id, pq.Array(action.From))
+	if err != nil {
+		log.Println("Error updating disbursements:", err)
+		return 0, err
+	}
+
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error checking rows affected: %v", err)
+	}
+	return changed, nil
+}
+
+// scheduleDisbursements schedules every instalment of the benefits of the application approved on the
+// date. Instalments already scheduled are kept as they are.
+func (ac *Application) scheduleDisbursements(ctx context.Context, tx *sql.Tx, approvedOn time.Time) error {
+	query := `SELECT id, benefit_id, benefit_name, benefit_amount, frequency, instalments, start_rule, TO_CHAR(start_date, 'YYYY-MM-DD') FROM application_details WHERE application_id = $1 AND benefit_amount IS NOT NULL`
+	rows, err := tx.QueryContext(ctx, query, ac.Id)
+	if err != nil {
+		log.Println("Error querying application details:", err)
+		return err
+	}
+
+	details := []ApplicationDetail{}
+	for rows.Next() {
+		var detail ApplicationDetail
+		var benefitId *uuid.UUID
+		if err := rows.Scan(&detail.Id, &benefitId, &detail.BenefitName, &detail.BenefitAmount, &detail.Frequency, &detail.Instalments, &detail.StartRule, &detail.StartDate); err != nil {
+			rows.Close()
+			log.Println("Error scanning application detail row:", err)
+			return err
+		}
+		if benefitId != nil {
+			detail.BenefitId = *benefitId
+		}
+		details = append(details, detail)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return err
+	}
+
+	insert := `INSERT INTO disbursements (application_id, application_detail_id, benefit_id, benefit_name, instalment, amount, due_date, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (application_detail_id, instalment) DO NOTHING`
+	for _, detail := range details {
+		var benefitId *uuid.UUID
+		if detail.BenefitId != uuid.Nil {
+			benefitId = &detail.BenefitId
+		}
+
+		for i, due := range detail.DueDates(approvedOn) {
+			if _, err := tx.ExecContext(ctx, insert, ac.Id, detail.Id, benefitId, detail.BenefitName, i+1, detail.BenefitAmount, due.Format("2006-01-02"), config.DisbursementScheduled); err != nil {
+				log.Println("Error inserting disbursement:", err)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,289 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"oneCV/config"
+	"oneCV/eligibility"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type EducationLevel struct {
+	Id       uuid.UUID `json:"id"`
+	Name     string    `json:"name" binding:"required"`
+	BandType string    `json:"band_type" binding:"required"`
+	Min      *int      `json:"min"`
+	Max      *int      `json:"max"`
+}
+
+func (e *EducationLevel) GetAllEducationLevels(ctx context.Context, db *sql.DB) ([]EducationLevel, error) {
+	return e.FetchEducationLevels(ctx, db, "")
+}
+
+func (e *EducationLevel) FetchEducationLevels(ctx context.Context, db *sql.DB, whereClause string, args ...interface{}) ([]EducationLevel, error) {
+	query := `SELECT id, name, band_type, min_value, max_value FROM education_levels WHERE deleted = false ` + whereClause + ` ORDER BY min_value NULLS FIRST, name`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Println("Error querying education levels:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := []EducationLevel{}
+	for rows.Next() {
+		var level EducationLevel
+		if err := rows.Scan(&level.Id, &level.Name, &level.BandType, &level.Min, &level.Max); err != nil {
+			log.Println("Error scanning education level row:", err)
+			return nil, err
+		}
+		levels = append(levels, level)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return nil, err
+	}
+
+	return levels, nil
+}
+
+// unique index on the name of the education levels that are not deleted
+const educationLevelNameIndex = "idx_education_levels_name"
+
+func educationLevelNotFound(id uuid.UUID) *Error {
+	return NotFoundError(CodeEducationLevelNotFound, config.EDUCATION_LEVEL_NOT_FOUND, map[string]interface{}{"id": id})
+}
+
+func (e *EducationLevel) CreateEducationLevel(ctx context.Context, db *sql.DB) error {
+	query := `INSERT INTO education_levels (name, band_type, min_value, max_value) VALUES ($1, $2, $3, $4) RETURNING id`
+	err := db.QueryRowContext(ctx, query, strings.ToLower(e.Name), strings.ToLower(e.BandType), e.Min, e.Max).Scan(&e.Id)
+	if isEducationLevelNameViolation(err) {
+		return e.nameTaken()
+	}
+	if err != nil {
+		log.Println("Error inserting education level:", err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateEducationLevel changes the level. A level cannot be renamed while schemes refer to it by name.
+func (e *EducationLevel) UpdateEducationLevel(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	name, err := e.lock(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if name != strings.ToLower(e.Name) {
+		if err := checkEducationLevelUnused(ctx, tx, e.Id, name); err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE education_levels SET name = $1, band_type = $2, min_value = $3, max_value = $4, updated_at = $5 WHERE id = $6 AND deleted = false`
+	_, err = tx.ExecContext(ctx, query, strings.ToLower(e.Name), strings.ToLower(e.BandType), e.Min, e.Max, time.Now(), e.Id)
+	if isEducationLevelNameViolation(err) {
+		return e.nameTaken()
+	}
+	if err != nil {
+		log.Println("Error updating education level:", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// lock locks the level until the transaction ends and returns its name.
+func (e *EducationLevel) lock(ctx context.Context, tx *sql.Tx) (string, error) {
+	var name string
+	err := tx.QueryRowContext(ctx, `SELECT name FROM education_levels WHERE id = $1 AND deleted = false FOR UPDATE`, e.Id).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", educationLevelNotFound(e.Id)
+	}
+	if err != nil {
+		log.Println("Error locking education level:", err)
+		return "", err
+	}
+	return name, nil
+}
+
+func (e *EducationLevel) nameTaken() *Error {
+	return ConflictError(CodeEducationLevelExists, config.EDUCATION_LEVEL_EXISTS, map[string]interface{}{"name": strings.ToLower(e.Name)})
+}
+
+func isEducationLevelNameViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == educationLevelNameIndex
+}
+
+// checkEducationLevelUnused returns a conflict error listing the schemes whose rule, criteria or draft
+// refer to the level by name. Evaluating them would fail once the name is gone.
+func checkEducationLevelUnused(ctx context.Context, tx *sql.Tx, id uuid.UUID, name string) error {
+	// the rows that mention the name are narrowed in SQL, then parsed to match only has_children conditions
+	query := `SELECT s.id, $2, s.rule::TEXT FROM schemes s WHERE s.deleted = false AND s.rule IS NOT NULL AND POSITION($1 IN s.rule::TEXT) > 0
+UNION ALL SELECT c.scheme_id, c.criteria_key, c.criteria_value FROM criteria c INNER JOIN schemes s ON c.scheme_id = s.id AND s.deleted = false WHERE c.deleted = false AND POSITION($1 IN c.criteria_value) > 0
+UNION ALL SELECT sv.scheme_id, $3, sv.definition::TEXT FROM scheme_versions sv INNER JOIN schemes s ON sv.scheme_id = s.id AND s.deleted = false WHERE sv.status = $4 AND POSITION($1 IN LOWER(sv.definition::TEXT)) > 0`
+
+	rows, err := tx.QueryContext(ctx, query, name, eligibility.RuleKey, config.VersionDraft, config.VersionDraft)
+	if err != nil {
+		log.Println("Error querying education level usage:", err)
+		return err
+	}
+	defer rows.Close()
+
+	schemeIds := []uuid.UUID{}
+	seen := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var schemeId uuid.UUID
+		var key, value string
+		if err := rows.Scan(&schemeId, &key, &value); err != nil {
+			log.Println("Error scanning education level usage row:", err)
+			return err
+		}
+
+		names := []string{}
+		if key == config.VersionDraft {
+			var req SchemeRequest
+			if err := json.Unmarshal([]byte(value), &req); err != nil {
+				log.Printf("Error parsing draft of scheme %s: %v", schemeId, err)
+				continue
+			}
+			names = req.SchoolLevels()
+		} else {
+			rule, err := eligibility.ParseCriteria(key, value)
+			if err != nil {
+				log.Printf("Error parsing rule of scheme %s: %v", schemeId, err)
+				continue
+			}
+			names = eligibility.SchoolLevelNames(rule)
+		}
+
+		for _, n := range names {
+			if n == name && !seen[schemeId] {
+				seen[schemeId] = true
+				schemeIds = append(schemeIds, schemeId)
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return err
+	}
+
+	if len(schemeIds) > 0 {
+		return ConflictError(CodeEducationLevelInUse, config.EDUCATION_LEVEL_IN_USE, map[string]interface{}{"id": id, "name": name, "scheme_ids": schemeIds})
+	}
+	return nil
+}
+
+func (e *EducationLevel) CheckEducationLevelExist(ctx context.Context, db *sql.DB) error {
+	query := `SELECT EXISTS(SELECT 1 from education_levels WHERE id = $1 AND deleted = false)`
+	var exists bool
+	err := db.QueryRowContext(ctx, query, e.Id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("error checking education level existence: %v", err)
+	}
+	if !exists {
+		return educationLevelNotFound(e.Id)
+	}
+
+	return nil
+}
+
+// CheckEducationLevelsExist returns an error naming the first level that is not configured.
+func (e *EducationLevel) CheckEducationLevelsExist(ctx context.Context, db *sql.DB, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	levels, err := e.FetchEducationLevels(ctx, db, ` AND name = ANY($1)`, pq.Array(names))
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool)
+	for _, level := range levels {
+		existing[level.Name] = true
+	}
+
+	for _, name := range names {
+		if !existing[name] {
+			return &Error{Kind: KindValidation, Code: CodeUnknownEducationLevel, Message: config.UNKNOWN_EDUCATION_LEVEL, Details: map[string]interface{}{"name": name}}
+		}
+	}
+
+	return nil
+}
+
+// DeleteEducationLevel deletes the level unless schemes refer to it by name.
+func (e *EducationLevel) DeleteEducationLevel(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	name, err := e.lock(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if err := checkEducationLevelUnused(ctx, tx, e.Id, name); err != nil {
+		return err
+	}
+
+	query := `UPDATE education_levels SET deleted = $1, updated_at = $2 WHERE id = $3 AND deleted = false`
+	if _, err := tx.ExecContext(ctx, query, true, time.Now(), e.Id); err != nil {
+		log.Println("Error deleting education level:", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (e *EducationLevel) SchoolLevel() eligibility.SchoolLevel {
+	return eligibility.SchoolLevel{Name: e.Name, BandType: e.BandType, Min: e.Min, Max: e.Max}
+}
+
+// NewEvaluator builds an eligibility evaluator from the configured education levels.
+func NewEvaluator(ctx context.Context, db *sql.DB) (eligibility.Evaluator, error) {
+	level := EducationLevel{}
+	levels, err := level.GetAllEducationLevels(ctx, db)
+	if err != nil {
+		return eligibility.Evaluator{}, err
+	}
+
+	schoolLevels := []eligibility.SchoolLevel{}
+	for _, l := range levels {
+		schoolLevels = append(schoolLevels, l.SchoolLevel())
+	}
+
+	return eligibility.NewEvaluator(schoolLevels), nil
+}
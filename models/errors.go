@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"oneCV/config"
+)
+
+// ErrorKind groups errors by how the API reports them.
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindPreconditionFailed
+	KindPreconditionRequired
+)
+
+// error codes are part of the API response, clients match on them so they must not change
+const (
+	CodeInternal                = "internal_error"
+	CodeInvalidRequest          = "invalid_request"
+	CodeValidationFailed        = "validation_failed"
+	CodeInvalidListQuery        = "invalid_list_query"
+	CodeApplicantNotFound       = "applicant_not_found"
+	CodeSchemeNotFound          = "scheme_not_found"
+	CodeApplicationNotFound     = "application_not_found"
+	CodeEducationLevelNotFound  = "education_level_not_found"
+	CodeHouseholdMemberNotFound = "household_member_not_found"
+	CodeSchemeVersionNotFound   = "scheme_version_not_found"
+	CodeSchemeDraftNotFound     = "scheme_draft_not_found"
+	CodeUnknownEducationLevel   = "unknown_education_level"
+	CodeEducationLevelInUse     = "education_level_in_use"
+	CodeEducationLevelExists    = "education_level_exists"
+	CodeNotEligible             = "not_eligible"
+	CodeDuplicateApplication    = "duplicate_application"
+	CodeInvalidTransition       = "invalid_status_transition"
+	CodeApplicationsClosed      = "applications_closed"
+	CodeSchemeNotActive         = "scheme_not_active"
+	CodeBudgetExceeded          = "budget_exceeded"
+	CodeRelationshipConflict    = "scheme_relationship_conflict"
+	CodeLedgerEntryNotFound     = "ledger_entry_not_found"
+	CodeDisbursementNotFound    = "disbursement_not_found"
+	CodePayoutNotAllowed        = "payout_not_allowed"
+	CodeIdempotencyKeyReused    = "idempotency_key_reused"
+	CodeApplicationHasPayouts   = "application_has_payouts"
+	CodeLedgerUnbalanced        = "ledger_unbalanced"
+	CodePreconditionFailed      = "precondition_failed"
+	CodePreconditionRequired    = "precondition_required"
+)
+
+// RuleUnknownId is the field error rule for an id that does not belong to the resource being updated.
+const RuleUnknownId = "unknown_id"
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string      `json:"field"`
+	Rule    string      `json:"rule"`
+	Message string      `json:"message,omitempty"`
+	Value   interface{} `json:"value,omitempty"`
+}
+
+// Error is an error the API can report to clients. Err keeps the underlying cause for logging
+// and is never sent in the response.
+type Error struct {
+	Kind    ErrorKind              `json:"-"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	Fields  []FieldError           `json:"fields,omitempty"`
+	Err     error                  `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NotFoundError(code string, message string, details map[string]interface{}) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message, Details: details}
+}
+
+func ConflictError(code string, message string, details map[string]interface{}) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message, Details: details}
+}
+
+func ValidationError(code string, message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields}
+}
+
+func PreconditionFailedError(code string, message string, details map[string]interface{}) *Error {
+	return &Error{Kind: KindPreconditionFailed, Code: code, Message: message, Details: details}
+}
+
+func PreconditionRequiredError(code string, message string) *Error {
+	return &Error{Kind: KindPreconditionRequired, Code: code, Message: message}
+}
+
+func InternalError(err error) *Error {
+	return &Error{Kind: KindInternal, Code: CodeInternal, Message: config.INTERNAL_ERROR, Err: err}
+}
+
+// AsError returns the *Error in err's chain, anything else is reported as an internal error.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return InternalError(err)
+}
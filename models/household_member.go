@@ -0,0 +1,188 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"oneCV/config"
+	"oneCV/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func householdMemberNotFound(id uuid.UUID) *Error {
+	return NotFoundError(CodeHouseholdMemberNotFound, config.HOUSEHOLD_MEMBER_NOT_FOUND, map[string]interface{}{"id": id})
+}
+
+func (s *Applicant) GetHouseholdMembers(ctx context.Context, db *sql.DB) ([]HouseholdMember, error) {
+	query := `SELECT id, name, relation, TO_CHAR(date_of_birth, 'YYYY-MM-DD'), employment_status, sex, monthly_income FROM household_members WHERE applicant_id = $1 ORDER BY created_at, id`
+
+	rows, err := db.QueryContext(ctx, query, s.Id)
+	if err != nil {
+		log.Println("Error querying household members:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []HouseholdMember{}
+	for rows.Next() {
+		var member HouseholdMember
+		if err := rows.Scan(&member.Id, &member.Name, &member.Relation, &member.DateOfBirth, &member.EmploymentStatus, &member.Sex, &member.MonthlyIncome); err != nil {
+			log.Println("Error scanning household member row:", err)
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// AddHouseholdMember inserts a single member, setting its new id on member. The applicant must still be
+// at its version when one is set.
+func (s *Applicant) AddHouseholdMember(ctx context.Context, db *sql.DB, member *HouseholdMember) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var version int
+	err = tx.QueryRowContext(ctx, `SELECT version FROM applicants WHERE id = $1 AND deleted = false FOR UPDATE`, s.Id).Scan(&version)
+	if err == sql.ErrNoRows {
+		return applicantNotFound(s.Id)
+	}
+	if err != nil {
+		log.Println("Error locking applicant:", err)
+		return err
+	}
+
+	if err := CheckVersion(s.Id, version, s.Version); err != nil {
+		return err
+	}
+
+	if err := member.insert(ctx, tx, s.Id); err != nil {
+		return err
+	}
+
+	if err := s.touch(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateHouseholdMember updates a member in place, keeping its id.
+func (s *Applicant) UpdateHouseholdMember(ctx context.Context, db *sql.DB, member *HouseholdMember) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := member.update(ctx, tx, s.Id); err != nil {
+		return err
+	}
+
+	if err := s.touch(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
+
+func (s *Applicant) DeleteHouseholdMember(ctx context.Context, db *sql.DB, memberId uuid.UUID) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM household_members WHERE id = $1 AND applicant_id = $2`
+	result, err := tx.ExecContext(ctx, query, memberId, s.Id)
+	if err != nil {
+		log.Println("Error deleting household member:", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return householdMemberNotFound(memberId)
+	}
+
+	if err := s.touch(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing transaction:", err)
+		return err
+	}
+	return nil
+}
+
+func (m *HouseholdMember) insert(ctx context.Context, tx *sql.Tx, applicantId uuid.UUID) error {
+	query := `INSERT INTO household_members (applicant_id, name, date_of_birth, relation, employment_status, sex, monthly_income) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	err := tx.QueryRowContext(ctx, query, applicantId, m.Name, m.DateOfBirth, m.Relation, strings.ToLower(utils.StringValue(m.EmploymentStatus)), strings.ToLower(utils.StringValue(m.Sex)), m.MonthlyIncome).Scan(&m.Id)
+	if err != nil {
+		log.Println("Error inserting household member:", err)
+		return err
+	}
+	return nil
+}
+
+func (m *HouseholdMember) update(ctx context.Context, tx *sql.Tx, applicantId uuid.UUID) error {
+	query := `UPDATE household_members SET name = $1, date_of_birth = $2, relation = $3, employment_status = $4, sex = $5, monthly_income = $6, updated_at = $7 WHERE id = $8 AND applicant_id = $9`
+
+	result, err := tx.ExecContext(ctx, query, m.Name, m.DateOfBirth, m.Relation, strings.ToLower(utils.StringValue(m.EmploymentStatus)), strings.ToLower(utils.StringValue(m.Sex)), m.MonthlyIncome, time.Now(), m.Id, applicantId)
+	if err != nil {
+		log.Println("Error updating household member:", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return householdMemberNotFound(m.Id)
+	}
+	return nil
+}
+
+// touch marks the applicant as updated when only its household changed, the household is part of
+// the applicant so its version is checked and bumped too.
+func (s *Applicant) touch(ctx context.Context, tx *sql.Tx) error {
+	query := `UPDATE applicants SET updated_at = $1, version = version + 1 WHERE id = $2 AND deleted = false AND ($3 = 0 OR version = $3) RETURNING version`
+	err := tx.QueryRowContext(ctx, query, time.Now(), s.Id, s.Version).Scan(&s.Version)
+	if err == sql.ErrNoRows {
+		return missedWrite(s.Id, s.Version, applicantNotFound(s.Id))
+	}
+	if err != nil {
+		log.Println("Error updating applicant:", err)
+		return err
+	}
+	return nil
+}
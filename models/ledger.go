@@ -0,0 +1,585 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"oneCV/config"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// unique index on ledger_entries.idempotency_key, a key posts at most one entry
+const idempotencyIndex = "idx_ledger_entries_idempotency"
+
+// LedgerEntry records a payout of a disbursement. A payout is scheduled when it is sent, then paid or
+// failed. A paid payout is never changed, reversing it posts a reversed entry of the negated amount.
+// Paid and reversed entries carry balanced double-entry Postings between the scheme fund and the beneficiary.
+type LedgerEntry struct {
+	Id                  uuid.UUID       `json:"id"`
+	DisbursementId      uuid.UUID       `json:"disbursement_id"`
+	ApplicationId       uuid.UUID       `json:"application_id"`
+	ApplicationDetailId uuid.UUID       `json:"application_detail_id"`
+	BenefitId           *uuid.UUID      `json:"benefit_id"`
+	Amount              float64         `json:"amount"`
+	EntryDate           string          `json:"entry_date"`
+	Reference           string          `json:"reference"`
+	Status              string          `json:"status"`
+	ReversalOf          *uuid.UUID      `json:"reversal_of"`
+	ReversedAt          *string         `json:"reversed_at"`
+	CreatedAt           string          `json:"created_at"`
+	UpdatedAt           string          `json:"updated_at"`
+	Postings            []LedgerPosting `json:"postings"`
+}
+
+// LedgerPosting is one leg of a ledger entry, a positive amount debited or credited to an account.
+type LedgerPosting struct {
+	Account string  `json:"account"`
+	Side    string  `json:"side"`
+	Amount  float64 `json:"amount"`
+}
+
+// PayoutRequest posts a payout of the whole amount of a disbursement. Date defaults to today.
+type PayoutRequest struct {
+	DisbursementId uuid.UUID `json:"disbursement_id"`
+	Status         string    `json:"status"`
+	Date           string    `json:"date"`
+	Reference      string    `json:"reference"`
+}
+
+// SettleRequest records the outcome of a scheduled payout, an empty date or reference keeps the one posted.
+type SettleRequest struct {
+	Status    string `json:"status"`
+	Date      string `json:"date"`
+	Reference string `json:"reference"`
+}
+
+type ReversalRequest struct {
+	Date      string `json:"date"`
+	Reference string `json:"reference"`
+}
+
+// status a disbursement takes from its latest ledger entry
+var disbursementPayoutStatus = map[string]string{
+	config.LedgerScheduled: config.DisbursementProcessing,
+	config.LedgerPaid:      config.DisbursementPaid,
+	config.LedgerFailed:    config.DisbursementFailed,
+	config.LedgerReversed:  config.DisbursementReversed,
+}
+
+// disbursements that can be paid out: never paid, failed or reversed
+var payableDisbursement = map[string]bool{
+	config.DisbursementScheduled: true,
+	config.DisbursementFailed:    true,
+	config.DisbursementReversed:  true,
+}
+
+// entryPostings returns the legs of an entry: a paid payout debits the beneficiary and credits the scheme
+// fund, a reversal does the opposite, and an entry that moved no money has none.
+func entryPostings(status string, amount float64) []LedgerPosting {
+	amount = math.Abs(amount)
+	if amount == 0 {
+		return []LedgerPosting{}
+	}
+
+	switch status {
+	case config.LedgerPaid:
+		return []LedgerPosting{
+			{Account: config.AccountBeneficiaryPayable, Side: config.SideDebit, Amount: amount},
+			{Account: config.AccountSchemeFund, Side: config.SideCredit, Amount: amount},
+		}
+	case config.LedgerReversed:
+		return []LedgerPosting{
+			{Account: config.AccountSchemeFund, Side: config.SideDebit, Amount: amount},
+			{Account: config.AccountBeneficiaryPayable, Side: config.SideCredit, Amount: amount},
+		}
+	}
+	return []LedgerPosting{}
+}
+
+// postingTotals returns the debited and credited totals of the postings in cents.
+func postingTotals(postings []LedgerPosting) (debit int64, credit int64) {
+	for _, p := range postings {
+		if p.Side == config.SideDebit {
+			debit += cents(p.Amount)
+		} else {
+			credit += cents(p.Amount)
+		}
+	}
+	return debit, credit
+}
+
+// post writes the postings of the entry, refusing legs that do not balance.
+func (e *LedgerEntry) post(ctx context.Context, tx *sql.Tx) error {
+	postings := entryPostings(e.Status, e.Amount)
+	if debit, credit := postingTotals(postings); debit != credit {
+		return fmt.Errorf("ledger entry %s postings do not balance: debit %d, credit %d cents", e.Id, debit, credit)
+	}
+
+	query := `INSERT INTO ledger_postings (entry_id, account, side, amount) VALUES ($1, $2, $3, $4)`
+	for _, p := range postings {
+		if _, err := tx.ExecContext(ctx, query, e.Id, p.Account, p.Side, p.Amount); err != nil {
+			log.Println("Error inserting ledger posting:", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func ledgerEntryNotFound(id uuid.UUID) *Error {
+	return NotFoundError(CodeLedgerEntryNotFound, config.LEDGER_ENTRY_NOT_FOUND, map[string]interface{}{"id": id})
+}
+
+func disbursementNotFound(id uuid.UUID) *Error {
+	return NotFoundError(CodeDisbursementNotFound, config.DISBURSEMENT_NOT_FOUND, map[string]interface{}{"id": id})
+}
+
+func payoutNotAllowed(id uuid.UUID, status string) *Error {
+	return ConflictError(CodePayoutNotAllowed, config.PAYOUT_NOT_ALLOWED, map[string]interface{}{"id": id, "status": status})
+}
+
+// requestHash identifies what a key was used for, so a key sent again with another request is refused.
+func requestHash(operation string, req interface{}) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("marshal ledger request failed: %v", err)
+	}
+	sum := sha256.Sum256(append([]byte(operation+":"), body...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// entryDate returns the date of an entry, today when not given.
+func entryDate(date string) string {
+	if date == "" {
+		return time.Now().Format("2006-01-02")
+	}
+	return date
+}
+
+// PostPayout records a payout of the disbursement. Posting again with the same idempotency key and
+// request returns the entry already posted, replayed is then set.
+func (e *LedgerEntry) PostPayout(ctx context.Context, db *sql.DB, key string, req PayoutRequest) (replayed bool, err error) {
+	hash, err := requestHash("payout", req)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if found, err := e.replay(ctx, tx, key, hash); found || err != nil {
+		return found, err
+	}
+
+	// lock the disbursement so concurrent payouts of it are posted one after another
+	var status string
+	query := `SELECT application_id, application_detail_id, benefit_id, amount, status FROM disbursements WHERE id = $1 FOR UPDATE`
+	err = tx.QueryRowContext(ctx, query, req.DisbursementId).Scan(&e.ApplicationId, &e.ApplicationDetailId, &e.BenefitId, &e.Amount, &status)
+	if err == sql.ErrNoRows {
+		return false, disbursementNotFound(req.DisbursementId)
+	}
+	if err != nil {
+		log.Println("Error getting disbursement:", err)
+		return false, err
+	}
+	if !payableDisbursement[status] {
+		return false, payoutNotAllowed(req.DisbursementId, status)
+	}
+
+	e.DisbursementId = req.DisbursementId
+	e.Status, e.EntryDate, e.Reference = strings.ToLower(req.Status), entryDate(req.Date), req.Reference
+	if err := e.insert(ctx, tx, key, hash); err != nil {
+		if isIdempotencyViolation(err) {
+			// another request with the key was posted first
+			tx.Rollback()
+			return e.replayAfterConflict(ctx, db, key, hash)
+		}
+		return false, err
+	}
+
+	if err := e.post(ctx, tx); err != nil {
+		return false, err
+	}
+
+	if err := setDisbursementStatus(ctx, tx, e.DisbursementId, disbursementPayoutStatus[e.Status]); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return false, e.GetLedgerEntry(ctx, db)
+}
+
+// SettlePayout records whether a scheduled payout was paid or failed. Settling it again with the same
+// status changes nothing.
+func (e *LedgerEntry) SettlePayout(ctx context.Context, db *sql.DB, req SettleRequest) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	settled := strings.ToLower(req.Status)
+	err = tx.QueryRowContext(ctx, `SELECT disbursement_id, amount, status FROM ledger_entries WHERE id = $1 FOR UPDATE`, e.Id).Scan(&e.DisbursementId, &e.Amount, &status)
+	if err == sql.ErrNoRows {
+		return ledgerEntryNotFound(e.Id)
+	}
+	if err != nil {
+		log.Println("Error getting ledger entry:", err)
+		return err
+	}
+
+	if status != settled {
+		if status != config.LedgerScheduled {
+			return payoutNotAllowed(e.Id, status)
+		}
+
+		query := `UPDATE ledger_entries SET status = $1, entry_date = COALESCE(NULLIF($2, '')::DATE, entry_date), reference = COALESCE(NULLIF($3, ''), reference), updated_at = $4 WHERE id = $5`
+		if _, err := tx.ExecContext(ctx, query, settled, req.Date, req.Reference, time.Now(), e.Id); err != nil {
+			log.Println("Error settling ledger entry:", err)
+			return err
+		}
+
+		e.Status = settled
+		if err := e.post(ctx, tx); err != nil {
+			return err
+		}
+
+		if err := setDisbursementStatus(ctx, tx, e.DisbursementId, disbursementPayoutStatus[settled]); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return e.GetLedgerEntry(ctx, db)
+}
+
+// ReversePayout posts the reversal of a paid payout, the disbursement can then be paid out again.
+// Like posting a payout, a request replayed with the same idempotency key returns the reversal posted.
+func (e *LedgerEntry) ReversePayout(ctx context.Context, db *sql.DB, key string, req ReversalRequest) (replayed bool, err error) {
+	payoutId := e.Id
+	hash, err := requestHash("reversal:"+payoutId.String(), req)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if found, err := e.replay(ctx, tx, key, hash); found || err != nil {
+		return found, err
+	}
+
+	var reversedAt *time.Time
+	query := `SELECT disbursement_id, application_id, application_detail_id, benefit_id, amount, status, reversed_at FROM ledger_entries WHERE id = $1 FOR UPDATE`
+	err = tx.QueryRowContext(ctx, query, payoutId).Scan(&e.DisbursementId, &e.ApplicationId, &e.ApplicationDetailId, &e.BenefitId, &e.Amount, &e.Status, &reversedAt)
+	if err == sql.ErrNoRows {
+		return false, ledgerEntryNotFound(payoutId)
+	}
+	if err != nil {
+		log.Println("Error getting ledger entry:", err)
+		return false, err
+	}
+	if e.Status != config.LedgerPaid || reversedAt != nil {
+		status := e.Status
+		if reversedAt != nil {
+			status = config.LedgerReversed
+		}
+		return false, payoutNotAllowed(payoutId, status)
+	}
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `UPDATE ledger_entries SET reversed_at = $1, updated_at = $1 WHERE id = $2`, now, payoutId); err != nil {
+		log.Println("Error reversing ledger entry:", err)
+		return false, err
+	}
+
+	e.Amount = -e.Amount
+	e.Status, e.EntryDate, e.Reference, e.ReversalOf = config.LedgerReversed, entryDate(req.Date), req.Reference, &payoutId
+	if err := e.insert(ctx, tx, key, hash); err != nil {
+		if isIdempotencyViolation(err) {
+			tx.Rollback()
+			return e.replayAfterConflict(ctx, db, key, hash)
+		}
+		return false, err
+	}
+
+	if err := e.post(ctx, tx); err != nil {
+		return false, err
+	}
+
+	if err := setDisbursementStatus(ctx, tx, e.DisbursementId, config.DisbursementReversed); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return false, e.GetLedgerEntry(ctx, db)
+}
+
+func (e *LedgerEntry) insert(ctx context.Context, tx *sql.Tx, key string, hash string) error {
+	query := `INSERT INTO ledger_entries (disbursement_id, application_id, application_detail_id, benefit_id, amount, entry_date, reference, status, reversal_of, idempotency_key, request_hash) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+	err := tx.QueryRowContext(ctx, query, e.DisbursementId, e.ApplicationId, e.ApplicationDetailId, e.BenefitId, e.Amount, e.EntryDate, e.Reference, e.Status, e.ReversalOf, key, hash).Scan(&e.Id)
+	if err != nil && !isIdempotencyViolation(err) {
+		log.Println("Error inserting ledger entry:", err)
+	}
+	return err
+}
+
+// replay loads the entry posted with the idempotency key, found is false when the key is new. A key
+// used for another request is refused.
+func (e *LedgerEntry) replay(ctx context.Context, db querier, key string, hash string) (found bool, err error) {
+	var id uuid.UUID
+	var postedHash string
+	err = db.QueryRowContext(ctx, `SELECT id, request_hash FROM ledger_entries WHERE idempotency_key = $1`, key).Scan(&id, &postedHash)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		log.Println("Error getting ledger entry by idempotency key:", err)
+		return false, err
+	}
+	if postedHash != hash {
+		return false, ConflictError(CodeIdempotencyKeyReused, config.IDEMPOTENCY_KEY_REUSED, map[string]interface{}{"idempotency_key": key, "entry_id": id})
+	}
+
+	entries, err := fetchLedgerEntries(ctx, db, ` WHERE l.id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	if len(entries) == 0 {
+		return false, ledgerEntryNotFound(id)
+	}
+	*e = entries[0]
+	return true, nil
+}
+
+func (e *LedgerEntry) replayAfterConflict(ctx context.Context, db *sql.DB, key string, hash string) (bool, error) {
+	found, err := e.replay(ctx, db, key, hash)
+	if err == nil && !found {
+		err = fmt.Errorf("ledger entry with idempotency key %q not found after conflict", key)
+	}
+	return found, err
+}
+
+func (e *LedgerEntry) GetLedgerEntry(ctx context.Context, db *sql.DB) error {
+	entries, err := fetchLedgerEntries(ctx, db, ` WHERE l.id = $1`, e.Id)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return ledgerEntryNotFound(e.Id)
+	}
+	*e = entries[0]
+	return nil
+}
+
+// GetLedger returns the ledger entries of the application in the order they were posted.
+func (ac *Application) GetLedger(ctx context.Context, db *sql.DB) ([]LedgerEntry, error) {
+	return fetchLedgerEntries(ctx, db, ` WHERE l.application_id = $1`, ac.Id)
+}
+
+func fetchLedgerEntries(ctx context.Context, db querier, whereClause string, args ...interface{}) ([]LedgerEntry, error) {
+	query := `SELECT l.id, l.disbursement_id, l.application_id, l.application_detail_id, l.benefit_id, l.amount, TO_CHAR(l.entry_date, 'YYYY-MM-DD'), l.reference, l.status, l.reversal_of, TO_CHAR(l.reversed_at, 'YYYY-MM-DD HH24:MI:SS'), TO_CHAR(l.created_at, 'YYYY-MM-DD HH24:MI:SS'), TO_CHAR(l.updated_at, 'YYYY-MM-DD HH24:MI:SS') FROM ledger_entries l` + whereClause + ` ORDER BY l.created_at, l.id`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Println("Error querying ledger entries:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []LedgerEntry{}
+	for rows.Next() {
+		var e LedgerEntry
+		if err := rows.Scan(&e.Id, &e.DisbursementId, &e.ApplicationId, &e.ApplicationDetailId, &e.BenefitId, &e.Amount, &e.EntryDate, &e.Reference, &e.Status, &e.ReversalOf, &e.ReversedAt, &e.CreatedAt, &e.UpdatedAt); err != nil {
+			log.Println("Error scanning ledger entry row:", err)
+			return nil, err
+		}
+		e.Postings = []LedgerPosting{}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return nil, err
+	}
+	rows.Close()
+
+	if err := loadPostings(ctx, db, entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// loadPostings sets the postings of each of the entries, debits first.
+func loadPostings(ctx context.Context, db querier, entries []LedgerEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ids := []string{}
+	index := make(map[uuid.UUID]int)
+	for i, e := range entries {
+		ids = append(ids, e.Id.String())
+		index[e.Id] = i
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT entry_id, account, side, amount FROM ledger_postings WHERE entry_id = ANY($1) ORDER BY entry_id, side DESC, id`, pq.Array(ids))
+	if err != nil {
+		log.Println("Error querying ledger postings:", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryId uuid.UUID
+		var p LedgerPosting
+		if err := rows.Scan(&entryId, &p.Account, &p.Side, &p.Amount); err != nil {
+			log.Println("Error scanning ledger posting row:", err)
+			return err
+		}
+		if i, ok := index[entryId]; ok {
+			entries[i].Postings = append(entries[i].Postings, p)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return err
+	}
+	return nil
+}
+
+func setDisbursementStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, status string) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE disbursements SET status = $1, updated_at = $2 WHERE id = $3`, status, time.Now(), id); err != nil {
+		log.Println("Error updating disbursement status:", err)
+		return err
+	}
+	return nil
+}
+
+func isIdempotencyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == idempotencyIndex
+}
+
+// ReconciliationHeader is the header row of the reconciliation export.
+var ReconciliationHeader = []string{"entry_id", "entry_date", "status", "amount", "reference", "reversal_of", "disbursement_id", "instalment", "due_date", "application_id", "applicant_id", "applicant_name", "scheme_id", "scheme_name", "benefit_id", "benefit_name", "debit", "credit"}
+
+// ReconciliationRow is a ledger entry with what finance needs to match it against bank statements.
+type ReconciliationRow struct {
+	LedgerEntry
+	Instalment    int
+	DueDate       string
+	ApplicantId   uuid.UUID
+	ApplicantName string
+	SchemeId      uuid.UUID
+	SchemeName    string
+	BenefitName   string
+	// totals of the postings of the entry
+	Debit  float64
+	Credit float64
+}
+
+// spreadsheetText keeps a text cell from being read as a formula when the export is opened in a spreadsheet.
+func spreadsheetText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// Record returns the row as CSV fields in the order of ReconciliationHeader. Text cells from references
+// and names are escaped so a spreadsheet shows them as entered.
+func (r ReconciliationRow) Record() []string {
+	optional := func(id *uuid.UUID) string {
+		if id == nil {
+			return ""
+		}
+		return id.String()
+	}
+	return []string{
+		r.Id.String(), r.EntryDate, r.Status, strconv.FormatFloat(r.Amount, 'f', 2, 64), spreadsheetText(r.Reference), optional(r.ReversalOf),
+		r.DisbursementId.String(), strconv.Itoa(r.Instalment), r.DueDate, r.ApplicationId.String(),
+		r.ApplicantId.String(), spreadsheetText(r.ApplicantName), r.SchemeId.String(), spreadsheetText(r.SchemeName), optional(r.BenefitId), spreadsheetText(r.BenefitName),
+		strconv.FormatFloat(r.Debit, 'f', 2, 64), strconv.FormatFloat(r.Credit, 'f', 2, 64),
+	}
+}
+
+// unbalancedEntries returns the entries whose postings do not net to zero or do not move the amount of the entry.
+func unbalancedEntries(rows []ReconciliationRow) []uuid.UUID {
+	ids := []uuid.UUID{}
+	for _, r := range rows {
+		want := int64(0)
+		if len(entryPostings(r.Status, r.Amount)) > 0 {
+			want = cents(math.Abs(r.Amount))
+		}
+		if cents(r.Debit) != want || cents(r.Credit) != want {
+			ids = append(ids, r.Id)
+		}
+	}
+	return ids
+}
+
+// Reconciliation returns the ledger entries dated within the period, both dates inclusive, by date. The
+// export is refused when the postings of an entry do not balance.
+func (e *LedgerEntry) Reconciliation(ctx context.Context, db *sql.DB, from string, to string) ([]ReconciliationRow, error) {
+	query := `SELECT l.id, TO_CHAR(l.entry_date, 'YYYY-MM-DD'), l.status, l.amount, l.reference, l.reversal_of, l.disbursement_id, d.instalment, TO_CHAR(d.due_date, 'YYYY-MM-DD'), l.application_id, app.id, app.name, s.id, s.name, l.benefit_id, d.benefit_name, COALESCE(p.debit, 0), COALESCE(p.credit, 0) FROM ledger_entries l INNER JOIN disbursements d ON l.disbursement_id = d.id INNER JOIN applications a ON l.application_id = a.id INNER JOIN applicants app ON a.applicant_id = app.id INNER JOIN schemes s ON a.scheme_id = s.id LEFT JOIN (SELECT entry_id, SUM(CASE WHEN side = 'debit' THEN amount ELSE 0 END) AS debit, SUM(CASE WHEN side = 'credit' THEN amount ELSE 0 END) AS credit FROM ledger_postings GROUP BY entry_id) p ON p.entry_id = l.id WHERE l.entry_date BETWEEN $1::DATE AND $2::DATE ORDER BY l.entry_date, l.created_at, l.id`
+
+	rows, err := db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		log.Println("Error querying ledger reconciliation:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []ReconciliationRow{}
+	for rows.Next() {
+		var r ReconciliationRow
+		if err := rows.Scan(&r.Id, &r.EntryDate, &r.Status, &r.Amount, &r.Reference, &r.ReversalOf, &r.DisbursementId, &r.Instalment, &r.DueDate, &r.ApplicationId, &r.ApplicantId, &r.ApplicantName, &r.SchemeId, &r.SchemeName, &r.BenefitId, &r.BenefitName, &r.Debit, &r.Credit); err != nil {
+			log.Println("Error scanning ledger reconciliation row:", err)
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return nil, err
+	}
+
+	if unbalanced := unbalancedEntries(records); len(unbalanced) > 0 {
+		log.Printf("Ledger entries with unbalanced postings: %v", unbalanced)
+		return nil, ConflictError(CodeLedgerUnbalanced, config.LEDGER_UNBALANCED, map[string]interface{}{"entry_ids": unbalanced})
+	}
+
+	return records, nil
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSpreadsheetText(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"Tan Ah Kow", "Tan Ah Kow"},
+		{"=HYPERLINK(\"http://example.com\")", "'=HYPERLINK(\"http://example.com\")"},
+		{"+65 9123 4567", "'+65 9123 4567"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1:A2)", "'@SUM(A1:A2)"},
+		{"\t=1+1", "'\t=1+1"},
+		{"PAY-001", "PAY-001"},
+	}
+
+	for _, tt := range tests {
+		if got := spreadsheetText(tt.value); got != tt.want {
+			t.Errorf("spreadsheetText(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestReconciliationRecordKeepsAmounts(t *testing.T) {
+	row := ReconciliationRow{LedgerEntry: LedgerEntry{Amount: -150, Reference: "=1+1"}, ApplicantName: "@Mary"}
+	record := row.Record()
+
+	if record[3] != "-150.00" {
+		t.Errorf("amount = %q, want %q", record[3], "-150.00")
+	}
+	if record[4] != "'=1+1" {
+		t.Errorf("reference = %q, want %q", record[4], "'=1+1")
+	}
+	if record[11] != "'@Mary" {
+		t.Errorf("applicant name = %q, want %q", record[11], "'@Mary")
+	}
+}
+
+func TestEntryPostings(t *testing.T) {
+	tests := []struct {
+		status      string
+		amount      float64
+		wantDebit   string
+		wantCredit  string
+		wantPosting bool
+	}{
+		{"paid", 200, "beneficiary_payable", "scheme_fund", true},
+		{"reversed", -200, "scheme_fund", "beneficiary_payable", true},
+		{"scheduled", 200, "", "", false},
+		{"failed", 200, "", "", false},
+		{"paid", 0, "", "", false},
+	}
+
+	for _, tt := range tests {
+		postings := entryPostings(tt.status, tt.amount)
+		if !tt.wantPosting {
+			if len(postings) != 0 {
+				t.Errorf("entryPostings(%q, %v) = %v, want none", tt.status, tt.amount, postings)
+			}
+			continue
+		}
+
+		if len(postings) != 2 {
+			t.Fatalf("entryPostings(%q, %v) = %v, want a debit and a credit", tt.status, tt.amount, postings)
+		}
+		if postings[0].Side != "debit" || postings[0].Account != tt.wantDebit || postings[1].Side != "credit" || postings[1].Account != tt.wantCredit {
+			t.Errorf("entryPostings(%q, %v) = %v, want debit %s and credit %s", tt.status, tt.amount, postings, tt.wantDebit, tt.wantCredit)
+		}
+		if debit, credit := postingTotals(postings); debit != credit || debit != 20000 {
+			t.Errorf("entryPostings(%q, %v) totals = %d debit, %d credit, want 20000 each", tt.status, tt.amount, debit, credit)
+		}
+	}
+}
+
+func TestUnbalancedEntries(t *testing.T) {
+	paid := ReconciliationRow{LedgerEntry: LedgerEntry{Id: uuid.New(), Status: "paid", Amount: 120.5}, Debit: 120.5, Credit: 120.5}
+	reversal := ReconciliationRow{LedgerEntry: LedgerEntry{Id: uuid.New(), Status: "reversed", Amount: -120.5}, Debit: 120.5, Credit: 120.5}
+	scheduled := ReconciliationRow{LedgerEntry: LedgerEntry{Id: uuid.New(), Status: "scheduled", Amount: 80}}
+	oneLeg := ReconciliationRow{LedgerEntry: LedgerEntry{Id: uuid.New(), Status: "paid", Amount: 80}, Debit: 80}
+	missing := ReconciliationRow{LedgerEntry: LedgerEntry{Id: uuid.New(), Status: "paid", Amount: 80}}
+	wrongAmount := ReconciliationRow{LedgerEntry: LedgerEntry{Id: uuid.New(), Status: "paid", Amount: 80}, Debit: 60, Credit: 60}
+	failedWithPostings := ReconciliationRow{LedgerEntry: LedgerEntry{Id: uuid.New(), Status: "failed", Amount: 80}, Debit: 80, Credit: 80}
+
+	if got := unbalancedEntries([]ReconciliationRow{paid, reversal, scheduled}); len(got) != 0 {
+		t.Errorf("unbalancedEntries of balanced rows = %v, want none", got)
+	}
+
+	got := unbalancedEntries([]ReconciliationRow{paid, oneLeg, missing, wrongAmount, failedWithPostings})
+	want := []uuid.UUID{oneLeg.Id, missing.Id, wrongAmount.Id, failedWithPostings.Id}
+	if len(got) != len(want) {
+		t.Fatalf("unbalancedEntries = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("unbalancedEntries[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
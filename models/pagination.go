@@ -0,0 +1,196 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"oneCV/config"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ListQuery holds the filters, sort and cursor of a list request.
+type ListQuery struct {
+	Limit  int
+	Cursor string
+	Sort   string
+	Desc   bool
+
+	conditions []string
+	args       []interface{}
+}
+
+type Page struct {
+	Limit      int    `json:"limit"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SortField is a column a list can be ordered by, with the type its cursor value is cast back to.
+type SortField struct {
+	Column string
+	Cast   string
+}
+
+type cursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	Id    uuid.UUID `json:"id"`
+}
+
+// Where adds a filter, the "?" in condition is replaced by the placeholder of value.
+func (q *ListQuery) Where(condition string, value interface{}) {
+	q.args = append(q.args, value)
+	q.conditions = append(q.conditions, strings.Replace(condition, "?", fmt.Sprintf("$%d", len(q.args)), 1))
+}
+
+// reasons a list query value cannot be used, returned in the details for clients to translate
+const (
+	ReasonInvalidLimit     = "invalid_limit"
+	ReasonInvalidOrder     = "invalid_order"
+	ReasonUnknownSortField = "unknown_sort_field"
+	ReasonMalformedCursor  = "malformed_cursor"
+	ReasonCursorMismatch   = "cursor_mismatch"
+	ReasonInvalidFilter    = "invalid_filter"
+)
+
+// InvalidListQueryError is returned for a list query value that cannot be used, such as an unknown sort field or a malformed cursor.
+// reason is one of the Reason codes, parameter the query parameter and value what was sent.
+func InvalidListQueryError(reason string, parameter string, value string) *Error {
+	return &Error{Kind: KindValidation, Code: CodeInvalidListQuery, Message: config.INVALID_LIST_QUERY, Details: map[string]interface{}{"reason": reason, "parameter": parameter, "value": value}}
+}
+
+func (q *ListQuery) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " AND " + strings.Join(q.conditions, " AND ")
+}
+
+// fetchPage returns the ids of one page of rows ordered by the sort field then id, and the cursor of the next page.
+// from is the FROM ... WHERE part of the query the filters are appended to.
+func (q *ListQuery) fetchPage(ctx context.Context, db *sql.DB, from string, idColumn string, fields map[string]SortField, defaultSort string) ([]uuid.UUID, string, error) {
+	sort := q.Sort
+	if sort == "" {
+		sort = defaultSort
+	}
+
+	field, ok := fields[sort]
+	if !ok {
+		return nil, "", InvalidListQueryError(ReasonUnknownSortField, "sort", q.Sort)
+	}
+
+	direction, operator := "ASC", ">"
+	if q.Desc {
+		direction, operator = "DESC", "<"
+	}
+
+	args := append([]interface{}{}, q.args...)
+	where := q.whereClause()
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if c.Sort != sort {
+			return nil, "", InvalidListQueryError(ReasonCursorMismatch, "cursor", q.Cursor)
+		}
+
+		args = append(args, c.Value, c.Id)
+		where += fmt.Sprintf(" AND (%s, %s) %s ($%d::%s, $%d::uuid)", field.Column, idColumn, operator, len(args)-1, field.Cast, len(args))
+	}
+
+	args = append(args, q.Limit+1)
+	query := fmt.Sprintf(`SELECT %s, %s::text %s%s ORDER BY %s %s, %s %s LIMIT $%d`, idColumn, field.Column, from, where, field.Column, direction, idColumn, direction, len(args))
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Println("Error querying page:", err)
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	values := []string{}
+	for rows.Next() {
+		var id uuid.UUID
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			log.Println("Error scanning page row:", err)
+			return nil, "", err
+		}
+		ids = append(ids, id)
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return nil, "", err
+	}
+
+	// the extra row only tells whether there is a next page
+	nextCursor := ""
+	if len(ids) > q.Limit {
+		ids = ids[:q.Limit]
+		nextCursor = encodeCursor(sort, values[q.Limit-1], ids[q.Limit-1])
+	}
+
+	return ids, nextCursor, nil
+}
+
+func (q *ListQuery) count(ctx context.Context, db *sql.DB, from string) (int, error) {
+	var total int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) `+from+q.whereClause(), q.args...).Scan(&total)
+	if err != nil {
+		log.Println("Error counting rows:", err)
+		return 0, err
+	}
+	return total, nil
+}
+
+func encodeCursor(sort string, value string, id uuid.UUID) string {
+	data, _ := json.Marshal(cursor{Sort: sort, Value: value, Id: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || json.Unmarshal(data, &c) != nil {
+		return c, InvalidListQueryError(ReasonMalformedCursor, "cursor", value)
+	}
+	return c, nil
+}
+
+func idStrings(ids []uuid.UUID) []string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id.String())
+	}
+	return values
+}
+
+// orderByIds puts items fetched with "id = ANY(...)" back in the order of the page ids.
+func orderByIds[T any](items []T, ids []uuid.UUID, id func(T) uuid.UUID) []T {
+	index := make(map[uuid.UUID]T, len(items))
+	for _, item := range items {
+		index[id(item)] = item
+	}
+
+	ordered := make([]T, 0, len(ids))
+	for _, pageId := range ids {
+		if item, ok := index[pageId]; ok {
+			ordered = append(ordered, item)
+		}
+	}
+	return ordered
+}
+
+// likePrefix escapes the LIKE wildcards of a prefix filter value.
+func likePrefix(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
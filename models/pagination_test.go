@@ -0,0 +1,101 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+var testSortFields = map[string]SortField{
+	"name":       {Column: "a.name", Cast: "text"},
+	"created_at": {Column: "a.created_at", Cast: "timestamp"},
+}
+
+// listQueryReason returns the reason of an invalid list query error, failing the test for any other error.
+func listQueryReason(t *testing.T, err error) string {
+	t.Helper()
+	e, ok := err.(*Error)
+	if !ok || e.Code != CodeInvalidListQuery {
+		t.Fatalf("error = %v, want an %s error", err, CodeInvalidListQuery)
+	}
+	return e.Details["reason"].(string)
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := uuid.New()
+	tests := []struct {
+		sort  string
+		value string
+	}{
+		{"name", "Mary Tan"},
+		{"name", ""},
+		{"name", "名字 \"quoted\" / slash"},
+		{"created_at", "2024-06-14 09:30:00"},
+		{"id", id.String()},
+	}
+
+	for _, tt := range tests {
+		encoded := encodeCursor(tt.sort, tt.value, id)
+		got, err := decodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("decodeCursor(encodeCursor(%q, %q)) returned error: %v", tt.sort, tt.value, err)
+		}
+		if got.Sort != tt.sort || got.Value != tt.value || got.Id != id {
+			t.Errorf("decodeCursor(encodeCursor(%q, %q)) = %+v", tt.sort, tt.value, got)
+		}
+	}
+}
+
+func TestDecodeMalformedCursor(t *testing.T) {
+	for _, value := range []string{"not base64!", "bm90IGpzb24", "eyJzIjoibmFtZSIsImlkIjoibm90LWEtdXVpZCJ9"} {
+		_, err := decodeCursor(value)
+		if err == nil {
+			t.Errorf("decodeCursor(%q) returned no error", value)
+			continue
+		}
+		if reason := listQueryReason(t, err); reason != ReasonMalformedCursor {
+			t.Errorf("decodeCursor(%q) reason = %q, want %q", value, reason, ReasonMalformedCursor)
+		}
+	}
+}
+
+// the invalid queries are rejected before the database is used, so no connection is needed
+func TestFetchPageInvalidQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      ListQuery
+		wantReason string
+	}{
+		{"unknown sort field", ListQuery{Limit: 20, Sort: "age"}, ReasonUnknownSortField},
+		{"cursor of another sort field", ListQuery{Limit: 20, Sort: "created_at", Cursor: encodeCursor("name", "Mary", uuid.New())}, ReasonCursorMismatch},
+		{"cursor without a sort field", ListQuery{Limit: 20, Sort: "created_at", Cursor: encodeCursor("", "Mary", uuid.New())}, ReasonCursorMismatch},
+		{"malformed cursor", ListQuery{Limit: 20, Cursor: "not base64!"}, ReasonMalformedCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.query.fetchPage(context.Background(), nil, "FROM applicants a WHERE a.deleted = false", "a.id", testSortFields, "name")
+			if err == nil {
+				t.Fatal("fetchPage returned no error")
+			}
+			if reason := listQueryReason(t, err); reason != tt.wantReason {
+				t.Errorf("fetchPage reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestListQueryWhere(t *testing.T) {
+	var q ListQuery
+	q.Where("a.status = ?", "pending")
+	q.Where("a.submitted_at >= ?::date", "2024-01-01")
+
+	want := " AND a.status = $1 AND a.submitted_at >= $2::date"
+	if got := q.whereClause(); got != want {
+		t.Errorf("whereClause = %q, want %q", got, want)
+	}
+	if len(q.args) != 2 || q.args[0] != "pending" || q.args[1] != "2024-01-01" {
+		t.Errorf("args = %v", q.args)
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"oneCV/config"
+	"oneCV/eligibility"
 	"strings"
 	"time"
 
@@ -15,11 +18,73 @@ import (
 )
 
 type Scheme struct {
-	Id          uuid.UUID              `json:"id"`
-	Name        string                 `json:"name"`
-	Description string                 `json:"-"`
-	Criteria    map[string]interface{} `json:"criteria"`
-	Benefits    []Benefit              `json:"benefits"`
+	SchemePeriod
+	SchemeFunding
+
+	Id                uuid.UUID            `json:"id"`
+	Name              string               `json:"name"`
+	Description       string               `json:"-"`
+	ApplicationPolicy string               `json:"application_policy"`
+	State             string               `json:"state"`
+	Rule              *eligibility.Rule    `json:"rule,omitempty"`
+	Criteria          []SchemeCriteria     `json:"criteria"`
+	Relationships     []SchemeRelationship `json:"relationships"`
+	// Conflicts are set on eligible schemes the applicant cannot apply for because of the schemes they hold
+	Conflicts     []RelationshipConflict `json:"conflicts,omitempty"`
+	VersionId     *uuid.UUID             `json:"version_id,omitempty"`
+	VersionNumber *int                   `json:"version_number,omitempty"`
+	// Version is the row version sent as ETag, not the published scheme version
+	Version int `json:"-"`
+	// invalid is why the stored rule or criteria of the scheme could not be parsed
+	invalid error
+}
+
+type SchemeCriteria struct {
+	Id         uuid.UUID        `json:"id"`
+	Conditions eligibility.Rule `json:"conditions"`
+	Benefits   []Benefit        `json:"benefits"`
+}
+
+type EligibleScheme struct {
+	Id       uuid.UUID        `json:"id"`
+	Name     string           `json:"name"`
+	Criteria []SchemeCriteria `json:"criteria"`
+}
+
+type SchemeImpact struct {
+	SchemeId         uuid.UUID              `json:"scheme_id"`
+	Applicants       int                    `json:"applicants"`
+	CurrentEligible  int                    `json:"current_eligible"`
+	ProposedEligible int                    `json:"proposed_eligible"`
+	Gained           int                    `json:"gained"`
+	Lost             int                    `json:"lost"`
+	GainedSample     []ApplicationApplicant `json:"gained_sample"`
+	LostSample       []ApplicationApplicant `json:"lost_sample"`
+}
+
+// number of applicants listed in each impact sample
+const impactSampleSize = 10
+
+type EligibilityReport struct {
+	SchemeId    uuid.UUID         `json:"scheme_id"`
+	ApplicantId uuid.UUID         `json:"applicant_id"`
+	Eligible    bool              `json:"eligible"`
+	Rule        eligibility.Trace `json:"rule"`
+	Criteria    []CriteriaReport  `json:"criteria"`
+}
+
+type CriteriaReport struct {
+	Id       uuid.UUID         `json:"id"`
+	Passed   bool              `json:"passed"`
+	Trace    eligibility.Trace `json:"trace"`
+	Benefits []Benefit         `json:"benefits"`
+}
+
+// applicant columns that string conditions can be matched against in SQL
+var applicantColumns = map[string]string{
+	"employment_status": "employment_status",
+	"marital_status":    "marital_status",
+	"sex":               "sex",
 }
 
 type Criteria struct {
@@ -37,30 +102,134 @@ type Benefit struct {
 	CriteriaId uuid.UUID `json:"-"`
 	Name       *string   `json:"name"`
 	Amount     *float64  `json:"amount"`
+
+	BenefitSchedule
 }
 
 type SchemeRequest struct {
-	Name        string            `json:"name" binding:"required"`
-	Description string            `json:"description"`
-	Criteria    []CriteriaRequest `json:"criteria"`
+	SchemePeriod
+	SchemeFunding
+
+	Name              string               `json:"name" binding:"required"`
+	Description       string               `json:"description"`
+	ApplicationPolicy string               `json:"application_policy"`
+	Rule              *eligibility.Rule    `json:"rule"`
+	Criteria          []CriteriaRequest    `json:"criteria"`
+	Relationships     []SchemeRelationship `json:"relationships"`
 }
 
+// CriteriaRequest and BenefitRequest carry the id of an existing row when updating a scheme,
+// rows sent without one are created.
 type CriteriaRequest struct {
-	Conditions map[string]interface{} `json:"conditions"`
-	Benefits   []BenefitRequest       `json:"benefits"`
+	Id         *uuid.UUID       `json:"id,omitempty"`
+	Conditions eligibility.Rule `json:"conditions"`
+	Benefits   []BenefitRequest `json:"benefits"`
 }
 
 type BenefitRequest struct {
-	Name   string  `json:"name"`
-	Amount float64 `json:"amount"`
+	Id     *uuid.UUID `json:"id,omitempty"`
+	Name   string     `json:"name"`
+	Amount float64    `json:"amount"`
+
+	BenefitSchedule
 }
 
-func (s *Scheme) GetAllSchemes(ctx context.Context, db *sql.DB) ([]Scheme, error) {
-	return s.FetchSchemes(ctx, db, "")
+func schemeNotFound(id uuid.UUID) *Error {
+	return NotFoundError(CodeSchemeNotFound, config.SCHEME_NOT_FOUND, map[string]interface{}{"id": id})
 }
 
-func (s *Scheme) FetchSchemes(ctx context.Context, db *sql.DB, whereClause string, args ...interface{}) ([]Scheme, error) {
-	query := `SELECT s.id, s.name, s.description, c.criteria_key, c.criteria_value, b.id AS b_id, b.name AS b_name, b.amount FROM schemes s LEFT JOIN criteria c ON s.id = c.scheme_id LEFT JOIN benefits b ON c.id = b.criteria_id WHERE s.deleted = false AND c.deleted = false AND b.deleted = false ` + whereClause + ` ORDER BY s.created_at DESC`
+var schemeSortFields = map[string]SortField{
+	"name":       {Column: "s.name", Cast: "text"},
+	"created_at": {Column: "s.created_at", Cast: "timestamp"},
+}
+
+// SchemeFilter narrows the scheme list, empty fields are ignored.
+type SchemeFilter struct {
+	Name              string
+	ApplicationPolicy string
+	// State is upcoming, active or expired on Date, a YYYY-MM-DD date
+	State string
+	Date  string
+}
+
+func (f SchemeFilter) Apply(q *ListQuery) {
+	if f.Name != "" {
+		q.Where("s.name ILIKE ? || '%'", likePrefix(f.Name))
+	}
+	if f.ApplicationPolicy != "" {
+		q.Where("s.application_policy = ?", strings.ToLower(f.ApplicationPolicy))
+	}
+
+	switch strings.ToLower(f.State) {
+	case config.SchemeUpcoming:
+		q.Where("s.effective_from > ?::DATE", f.Date)
+	case config.SchemeActive:
+		q.Where("(s.effective_from IS NULL OR s.effective_from <= ?::DATE)", f.Date)
+		q.Where("(s.effective_to IS NULL OR s.effective_to >= ?::DATE)", f.Date)
+	case config.SchemeExpired:
+		q.Where("s.effective_to < ?::DATE", f.Date)
+	}
+}
+
+func (s *Scheme) GetAllSchemes(ctx context.Context, db *sql.DB, q ListQuery) ([]Scheme, Page, error) {
+	from := `FROM schemes s WHERE s.deleted = false`
+	page := Page{Limit: q.Limit}
+
+	ids, nextCursor, err := q.fetchPage(ctx, db, from, "s.id", schemeSortFields, "created_at")
+	if err != nil {
+		return nil, page, err
+	}
+	page.NextCursor = nextCursor
+
+	page.Total, err = q.count(ctx, db, from)
+	if err != nil {
+		return nil, page, err
+	}
+
+	schemes, err := s.FetchSchemes(ctx, db, ` AND s.id = ANY($1)`, pq.Array(idStrings(ids)))
+	if err != nil {
+		return nil, page, err
+	}
+
+	return orderByIds(schemes, ids, func(scheme Scheme) uuid.UUID { return scheme.Id }), page, nil
+}
+
+func (s *Scheme) FetchSchemes(ctx context.Context, db querier, whereClause string, args ...interface{}) ([]Scheme, error) {
+	schemes, err := s.fetchSchemes(ctx, db, whereClause, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, scheme := range schemes {
+		if scheme.invalid != nil {
+			return nil, scheme.invalid
+		}
+	}
+	return schemes, nil
+}
+
+// fetchEvaluableSchemes returns every scheme, leaving out with a log line the ones whose stored rule or
+// criteria cannot be parsed so they do not stop the others being offered.
+func (s *Scheme) fetchEvaluableSchemes(ctx context.Context, db querier) ([]Scheme, error) {
+	schemes, err := s.fetchSchemes(ctx, db, "")
+	if err != nil {
+		return nil, err
+	}
+
+	evaluable := []Scheme{}
+	for _, scheme := range schemes {
+		if scheme.invalid != nil {
+			log.Printf("Error parsing scheme %s, skipping it: %v", scheme.Id, scheme.invalid)
+			continue
+		}
+		evaluable = append(evaluable, scheme)
+	}
+	return evaluable, nil
+}
+
+// fetchSchemes returns the schemes, setting invalid on those whose rule or criteria cannot be parsed.
+func (s *Scheme) fetchSchemes(ctx context.Context, db querier, whereClause string, args ...interface{}) ([]Scheme, error) {
+	query := `SELECT s.id, s.version, s.name, s.description, s.application_policy, TO_CHAR(s.effective_from, 'YYYY-MM-DD'), TO_CHAR(s.effective_to, 'YYYY-MM-DD'), TO_CHAR(s.application_from, 'YYYY-MM-DD'), TO_CHAR(s.application_to, 'YYYY-MM-DD'), s.budget, s.max_recipients, s.budget_policy, s.rule, sv.id AS sv_id, sv.number, c.id AS c_id, c.criteria_key, c.criteria_value, b.id AS b_id, b.name AS b_name, b.amount, b.frequency, b.instalments, b.start_rule, TO_CHAR(b.start_date, 'YYYY-MM-DD') FROM schemes s LEFT JOIN scheme_versions sv ON s.id = sv.scheme_id AND sv.status = 'published' LEFT JOIN criteria c ON s.id = c.scheme_id AND c.deleted = false LEFT JOIN benefits b ON c.id = b.criteria_id AND b.deleted = false WHERE s.deleted = false ` + whereClause + ` ORDER BY s.created_at DESC, s.id, c.created_at, c.id, b.created_at`
 
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -70,12 +239,16 @@ func (s *Scheme) FetchSchemes(ctx context.Context, db *sql.DB, whereClause strin
 	defer rows.Close()
 
 	schemeMap := make(map[uuid.UUID]*Scheme)
+	order := []uuid.UUID{}
 	for rows.Next() {
 		var scheme Scheme
+		var description, rule, criteriaKey, criteriaValue sql.NullString
 		var criteria Criteria
 		var benefit Benefit
+		var frequency, startRule sql.NullString
+		var instalments sql.NullInt64
 
-		err = rows.Scan(&scheme.Id, &scheme.Name, &scheme.Description, &criteria.CriteriaKey, &criteria.CriteriaValue, &benefit.Id, &benefit.Name, &benefit.Amount)
+		err = rows.Scan(&scheme.Id, &scheme.Version, &scheme.Name, &description, &scheme.ApplicationPolicy, &scheme.EffectiveFrom, &scheme.EffectiveTo, &scheme.ApplicationFrom, &scheme.ApplicationTo, &scheme.Budget, &scheme.MaxRecipients, &scheme.BudgetPolicy, &rule, &scheme.VersionId, &scheme.VersionNumber, &criteria.Id, &criteriaKey, &criteriaValue, &benefit.Id, &benefit.Name, &benefit.Amount, &frequency, &instalments, &startRule, &benefit.StartDate)
 		if err != nil {
 			log.Println("Error scanning row:", err)
 			return nil, err
@@ -83,59 +256,146 @@ func (s *Scheme) FetchSchemes(ctx context.Context, db *sql.DB, whereClause strin
 
 		if _, exists := schemeMap[scheme.Id]; !exists {
 			schemeMap[scheme.Id] = &Scheme{
-				Id:          scheme.Id,
-				Name:        scheme.Name,
-				Description: scheme.Description,
-				Criteria:    make(map[string]interface{}),
-				Benefits:    []Benefit{},
+				Id:                scheme.Id,
+				Version:           scheme.Version,
+				Name:              scheme.Name,
+				Description:       description.String,
+				ApplicationPolicy: scheme.ApplicationPolicy,
+				SchemePeriod:      scheme.SchemePeriod,
+				SchemeFunding:     scheme.SchemeFunding,
+				State:             scheme.StateOn(time.Now()),
+				VersionId:         scheme.VersionId,
+				VersionNumber:     scheme.VersionNumber,
+				Criteria:          []SchemeCriteria{},
+				Relationships:     []SchemeRelationship{},
 			}
-		}
+			order = append(order, scheme.Id)
 
-		if criteria.CriteriaKey != "" && criteria.CriteriaValue != "" {
-			var criteriaValue interface{}
-			err := json.Unmarshal([]byte(criteria.CriteriaValue), &criteriaValue)
-			if err != nil {
-				criteriaValue = criteria.CriteriaValue
+			if rule.Valid {
+				schemeRule, err := eligibility.ParseCriteria(eligibility.RuleKey, rule.String)
+				if err != nil {
+					log.Println("Error parsing scheme rule:", err)
+					schemeMap[scheme.Id].invalid = err
+				} else {
+					schemeMap[scheme.Id].Rule = &schemeRule
+				}
 			}
+		}
+		current := schemeMap[scheme.Id]
 
-			// check criteria exist
-			if _, exists := schemeMap[scheme.Id].Criteria[criteria.CriteriaKey]; !exists {
-				schemeMap[scheme.Id].Criteria[criteria.CriteriaKey] = criteriaValue
-			}
+		if criteria.Id == uuid.Nil {
+			continue
 		}
 
-		if benefit.Name != nil && benefit.Amount != nil {
-			if schemeMap[scheme.Id].Benefits == nil {
-				schemeMap[scheme.Id].Benefits = make([]Benefit, 0)
+		// check criteria exist
+		index := -1
+		for i, c := range current.Criteria {
+			if c.Id == criteria.Id {
+				index = i
+				break
 			}
+		}
 
-			// check benefit exist
-			exists := false
-			for _, b := range schemeMap[scheme.Id].Benefits {
-				if b.Id == benefit.Id {
-					exists = true
-					break
-				}
+		if index == -1 {
+			conditions, err := eligibility.ParseCriteria(criteriaKey.String, criteriaValue.String)
+			if err != nil {
+				log.Println("Error parsing criteria:", err)
+				current.invalid = err
 			}
 
-			if !exists {
-				schemeMap[scheme.Id].Benefits = append(schemeMap[scheme.Id].Benefits, Benefit{
-					Id:     benefit.Id,
-					Name:   benefit.Name,
-					Amount: benefit.Amount,
-				})
-			}
+			current.Criteria = append(current.Criteria, SchemeCriteria{
+				Id:         criteria.Id,
+				Conditions: conditions,
+				Benefits:   []Benefit{},
+			})
+			index = len(current.Criteria) - 1
+		}
+
+		if benefit.Name != nil && benefit.Amount != nil {
+			current.Criteria[index].Benefits = append(current.Criteria[index].Benefits, Benefit{
+				Id:         benefit.Id,
+				CriteriaId: criteria.Id,
+				Name:       benefit.Name,
+				Amount:     benefit.Amount,
+				BenefitSchedule: BenefitSchedule{
+					Frequency:   frequency.String,
+					Instalments: int(instalments.Int64),
+					StartRule:   startRule.String,
+					StartDate:   benefit.StartDate,
+				},
+			})
 		}
 	}
 
-	var schemes []Scheme
-	for _, scheme := range schemeMap {
-		schemes = append(schemes, *scheme)
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return nil, err
+	}
+
+	schemes := []Scheme{}
+	for _, id := range order {
+		schemes = append(schemes, *schemeMap[id])
+	}
+
+	if err := loadRelationships(ctx, db, schemes); err != nil {
+		return nil, err
 	}
 
 	return schemes, nil
 }
 
+func (s *Scheme) GetSchemeById(ctx context.Context, db *sql.DB) error {
+	whereClause := ` AND s.id = $1`
+	schemes, err := s.FetchSchemes(ctx, db, whereClause, s.Id)
+	if err != nil {
+		return err
+	}
+
+	if len(schemes) == 0 {
+		return schemeNotFound(s.Id)
+	}
+
+	*s = schemes[0]
+	return nil
+}
+
+// EligibilityRule returns the rule an applicant must satisfy to apply for the scheme.
+// Schemes without an explicit rule qualify when any of their criteria matches.
+func (s *Scheme) EligibilityRule() eligibility.Rule {
+	if s.Rule != nil {
+		return *s.Rule
+	}
+
+	rules := []eligibility.Rule{}
+	for _, c := range s.Criteria {
+		rules = append(rules, c.Conditions)
+	}
+	return eligibility.AnyOf(rules...)
+}
+
+// EligibleCriteria returns the criteria (and their benefits) the applicant qualifies for,
+// or nothing when the applicant does not satisfy the scheme rule.
+func (s *Scheme) EligibleCriteria(evaluator eligibility.Evaluator, profile eligibility.Profile) ([]SchemeCriteria, error) {
+	eligibleCriteria := []SchemeCriteria{}
+
+	ok, err := evaluator.Evaluate(s.EligibilityRule(), profile)
+	if err != nil || !ok {
+		return eligibleCriteria, err
+	}
+
+	for _, c := range s.Criteria {
+		ok, err := evaluator.Evaluate(c.Conditions, profile)
+		if err != nil {
+			return eligibleCriteria, err
+		}
+		if ok {
+			eligibleCriteria = append(eligibleCriteria, c)
+		}
+	}
+
+	return eligibleCriteria, nil
+}
+
 func (s *Scheme) CreateScheme(ctx context.Context, db *sql.DB, req SchemeRequest) error {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
@@ -144,9 +404,14 @@ func (s *Scheme) CreateScheme(ctx context.Context, db *sql.DB, req SchemeRequest
 	}
 	defer tx.Rollback()
 
-	query := `INSERT INTO schemes (name, description) VALUES ($1, $2) RETURNING id`
+	rule, err := req.RuleValue()
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO schemes (name, description, application_policy, effective_from, effective_to, application_from, application_to, budget, max_recipients, budget_policy, rule) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
 	var schemeID uuid.UUID
-	err = tx.QueryRowContext(ctx, query, req.Name, req.Description).Scan(&schemeID)
+	err = tx.QueryRowContext(ctx, query, req.Name, req.Description, req.Policy(), req.EffectiveFrom, req.EffectiveTo, req.ApplicationFrom, req.ApplicationTo, req.Budget, req.MaxRecipients, req.FundingPolicy(), rule).Scan(&schemeID)
 	if err != nil {
 		return fmt.Errorf("could not insert scheme: %v", err)
 	}
@@ -157,6 +422,14 @@ func (s *Scheme) CreateScheme(ctx context.Context, db *sql.DB, req SchemeRequest
 		return err
 	}
 
+	if err := s.saveRelationships(ctx, tx, req.Relationships); err != nil {
+		return err
+	}
+
+	if err := s.publish(ctx, tx, nil); err != nil {
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return fmt.Errorf("could not commit transaction: %v", err)
@@ -165,99 +438,250 @@ func (s *Scheme) CreateScheme(ctx context.Context, db *sql.DB, req SchemeRequest
 	return nil
 }
 
-func (s *Scheme) GetSchemeCriteria(ctx context.Context, db *sql.DB) ([]Criteria, error) {
-	query := `SELECT c.id, c.criteria_key, c.criteria_value FROM schemes s LEFT JOIN criteria c ON s.id = c.scheme_id WHERE s.id = $1 AND c.deleted = false AND s.deleted = false`
+// ExplainEligibility returns the pass/fail trace of the scheme rule and of every criteria for the applicant.
+func (s *Scheme) ExplainEligibility(evaluator eligibility.Evaluator, applicant Applicant) (EligibilityReport, error) {
+	profile := applicant.Profile()
+	report := EligibilityReport{SchemeId: s.Id, ApplicantId: applicant.Id, Criteria: []CriteriaReport{}}
 
-	rows, err := db.QueryContext(ctx, query, s.Id)
+	trace, err := evaluator.Explain(s.EligibilityRule(), profile)
 	if err != nil {
-		log.Println("Error querying schemes:", err)
-		return nil, err
+		return report, err
 	}
-	defer rows.Close()
-
-	var criteria []Criteria
+	report.Rule = trace
+	report.Eligible = trace.Passed
 
-	for rows.Next() {
-		var crit Criteria
-		err := rows.Scan(&crit.Id, &crit.CriteriaKey, &crit.CriteriaValue)
+	for _, c := range s.Criteria {
+		trace, err := evaluator.Explain(c.Conditions, profile)
 		if err != nil {
-			log.Println("Error scanning criteria row:", err)
-			return nil, err
+			return report, err
 		}
 
-		criteria = append(criteria, crit)
-	}
-
-	if err := rows.Err(); err != nil {
-		log.Println("Error with rows:", err)
-		return nil, err
+		report.Criteria = append(report.Criteria, CriteriaReport{
+			Id:       c.Id,
+			Passed:   report.Eligible && trace.Passed,
+			Trace:    trace,
+			Benefits: c.Benefits,
+		})
 	}
 
-	return criteria, nil
+	return report, nil
 }
 
-func (s *Scheme) GetEligibleSchemes(ctx context.Context, db *sql.DB, applicant Applicant) ([]Scheme, error) {
-	schemes := []Scheme{}
-	criteriaData, err := applicant.GetApplicantCriteriaData(ctx, db)
+func (s *Scheme) GetEligibleSchemes(ctx context.Context, db *sql.DB, evaluator eligibility.Evaluator, applicant Applicant) ([]Scheme, error) {
+	eligibleSchemes := []Scheme{}
+	schemes, err := s.fetchEvaluableSchemes(ctx, db)
 	if err != nil {
-		return schemes, err
+		return eligibleSchemes, err
 	}
 
-	var query strings.Builder
-	var args []interface{}
+	profile := applicant.Profile()
+	for _, scheme := range schemes {
+		// schemes that have not started or have lapsed are not offered
+		if !scheme.EffectiveOn(evaluator.Date()) {
+			continue
+		}
 
-	query.WriteString(" AND ( ")
+		// a scheme whose rule cannot be evaluated is left out rather than failing every other scheme
+		ok, err := evaluator.Evaluate(scheme.EligibilityRule(), profile)
+		if err != nil {
+			log.Printf("Error evaluating scheme %s, skipping it: %v", scheme.Id, err)
+			continue
+		}
 
-	for i, v := range criteriaData {
-		if i > 0 {
-			query.WriteString(" OR ")
+		if ok {
+			eligibleSchemes = append(eligibleSchemes, scheme)
 		}
-		query.WriteString("(c.criteria_key = $")
-		query.WriteString(fmt.Sprintf("%d", i*2+1))
-		query.WriteString(" AND c.criteria_value = $")
-		query.WriteString(fmt.Sprintf("%d", i*2+2))
-		query.WriteString(")")
+	}
 
-		args = append(args, v.CriteriaKey, v.CriteriaValue)
+	// eligible schemes the applicant cannot hold with the schemes they already hold are marked
+	ids := []uuid.UUID{}
+	for _, scheme := range eligibleSchemes {
+		ids = append(ids, scheme.Id)
+	}
+	conflicts, err := applicant.RelationshipConflicts(ctx, db, ids)
+	if err != nil {
+		return eligibleSchemes, err
+	}
+	for i := range eligibleSchemes {
+		eligibleSchemes[i].Conflicts = conflicts[eligibleSchemes[i].Id]
 	}
 
-	query.WriteString(" )")
+	return eligibleSchemes, nil
+}
 
-	schemes, err = s.FetchSchemes(ctx, db, query.String(), args...)
+// SimulateEligibility returns the schemes, with only the criteria and benefits that apply, an applicant
+// profile qualifies for. The applicant does not need to be saved.
+func (s *Scheme) SimulateEligibility(ctx context.Context, db *sql.DB, evaluator eligibility.Evaluator, applicant Applicant) ([]EligibleScheme, error) {
+	eligibleSchemes := []EligibleScheme{}
+	schemes, err := s.fetchEvaluableSchemes(ctx, db)
 	if err != nil {
-		return schemes, err
+		return eligibleSchemes, err
 	}
 
-	return schemes, nil
-}
+	profile := applicant.Profile()
+	for _, scheme := range schemes {
+		if !scheme.EffectiveOn(evaluator.Date()) {
+			continue
+		}
 
-func (s *Scheme) GetBenefitsByCriteriaIds(ctx context.Context, db *sql.DB, ids []uuid.UUID) ([]Benefit, error) {
-	query := `SELECT id, criteria_id, name, amount FROM benefits WHERE deleted = false AND criteria_id = ANY($1)`
+		eligibleCriteria, err := scheme.EligibleCriteria(evaluator, profile)
+		if err != nil {
+			log.Printf("Error evaluating scheme %s, skipping it: %v", scheme.Id, err)
+			continue
+		}
 
-	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
-	if err != nil {
-		log.Println("Error querying benefits:", err)
-		return nil, err
+		if len(eligibleCriteria) > 0 {
+			eligibleSchemes = append(eligibleSchemes, EligibleScheme{Id: scheme.Id, Name: scheme.Name, Criteria: eligibleCriteria})
+		}
 	}
-	defer rows.Close()
 
-	var benefits []Benefit
+	return eligibleSchemes, nil
+}
 
-	for rows.Next() {
-		var benefit Benefit
-		if err := rows.Scan(&benefit.Id, &benefit.CriteriaId, &benefit.Name, &benefit.Amount); err != nil {
-			log.Println("Error scanning benefit row:", err)
-			return nil, err
+// PreviewImpact evaluates the proposed scheme request against every applicant alongside the current
+// criteria, without saving anything. The applicants are read a batch at a time in id order, only the
+// counts and the samples are kept, and an applicant whose profile cannot be evaluated is logged and left out.
+func (s *Scheme) PreviewImpact(ctx context.Context, db *sql.DB, evaluator eligibility.Evaluator, req SchemeRequest) (SchemeImpact, error) {
+	impact := SchemeImpact{SchemeId: s.Id, GainedSample: []ApplicationApplicant{}, LostSample: []ApplicationApplicant{}}
+
+	proposed := req.Scheme()
+	currentRule := s.EligibilityRule()
+	proposedRule := proposed.EligibilityRule()
+
+	batch := ListQuery{Limit: eligibleBatchSize}
+	applicant := Applicant{}
+	for {
+		ids, nextBatch, err := batch.fetchPage(ctx, db, `FROM applicants a WHERE a.deleted = false`, "a.id", eligibleApplicantSortFields, "id")
+		if err != nil {
+			return impact, err
+		}
+
+		applicants, err := applicant.FetchApplicant(ctx, db, ` AND a.id = ANY($1)`, pq.Array(idStrings(ids)))
+		if err != nil {
+			return impact, err
 		}
-		benefits = append(benefits, benefit)
+
+		for _, a := range orderByIds(applicants, ids, func(a Applicant) uuid.UUID { return a.Id }) {
+			profile := a.Profile()
+			current, err := evaluator.Evaluate(currentRule, profile)
+			if err != nil {
+				log.Printf("Error evaluating applicant %s for scheme %s, skipping them: %v", a.Id, s.Id, err)
+				continue
+			}
+
+			next, err := evaluator.Evaluate(proposedRule, profile)
+			if err != nil {
+				log.Printf("Error evaluating applicant %s for the proposed scheme %s, skipping them: %v", a.Id, s.Id, err)
+				continue
+			}
+
+			impact.Applicants++
+			if current {
+				impact.CurrentEligible++
+			}
+			if next {
+				impact.ProposedEligible++
+			}
+
+			summary := ApplicationApplicant{Id: a.Id, Name: a.Name, EmploymentStatus: a.EmploymentStatus}
+			switch {
+			case next && !current:
+				impact.Gained++
+				if len(impact.GainedSample) < impactSampleSize {
+					impact.GainedSample = append(impact.GainedSample, summary)
+				}
+			case current && !next:
+				impact.Lost++
+				if len(impact.LostSample) < impactSampleSize {
+					impact.LostSample = append(impact.LostSample, summary)
+				}
+			}
+		}
+
+		if nextBatch == "" {
+			return impact, nil
+		}
+		batch.Cursor = nextBatch
 	}
+}
 
-	if err := rows.Err(); err != nil {
-		log.Println("Error iterating over rows:", err)
-		return nil, err
+// eligible applicants are listed by id, the only order their cursor can resume from without evaluating everyone
+var eligibleApplicantSortFields = map[string]SortField{
+	"id": {Column: "a.id", Cast: "uuid"},
+}
+
+// number of candidate applicants evaluated at a time while filling a page of eligible applicants
+const eligibleBatchSize = 200
+
+// GetEligibleApplicants returns one page of the applicants who satisfy the scheme rule and the cursor of the next page.
+// Plain string conditions that every match requires are pushed into the applicant query, the candidates are then
+// evaluated here a batch at a time until the page is full. A scheme not in effect on the evaluation date has no
+// eligible applicants and is refused, an applicant whose profile cannot be evaluated is logged and left out.
+func (s *Scheme) GetEligibleApplicants(ctx context.Context, db *sql.DB, evaluator eligibility.Evaluator, q ListQuery) ([]Applicant, Page, error) {
+	eligibleApplicants := []Applicant{}
+	page := Page{Limit: q.Limit}
+	rule := s.EligibilityRule()
+
+	if state := s.StateOn(evaluator.Date()); state != config.SchemeActive {
+		return eligibleApplicants, page, ConflictError(CodeSchemeNotActive, config.SCHEME_NOT_ACTIVE, map[string]interface{}{
+			"scheme_id":      s.Id,
+			"state":          state,
+			"date":           evaluator.Date().Format("2006-01-02"),
+			"effective_from": s.EffectiveFrom,
+			"effective_to":   s.EffectiveTo,
+		})
+	}
+
+	for key, value := range rule.RequiredConditions() {
+		column, ok := applicantColumns[key]
+		if !ok {
+			continue
+		}
+
+		if str, ok := value.(string); ok {
+			q.Where("a."+column+" = ?", strings.ToLower(str))
+		}
 	}
 
-	return benefits, nil
+	from := `FROM applicants a WHERE a.deleted = false`
+	batch := q
+	batch.Limit = eligibleBatchSize
+	applicant := Applicant{}
+	for {
+		ids, nextBatch, err := batch.fetchPage(ctx, db, from, "a.id", eligibleApplicantSortFields, "id")
+		if err != nil {
+			return eligibleApplicants, page, err
+		}
+
+		applicants, err := applicant.FetchApplicant(ctx, db, ` AND a.id = ANY($1)`, pq.Array(idStrings(ids)))
+		if err != nil {
+			return eligibleApplicants, page, err
+		}
+
+		for _, a := range orderByIds(applicants, ids, func(a Applicant) uuid.UUID { return a.Id }) {
+			ok, err := evaluator.Evaluate(rule, a.Profile())
+			if err != nil {
+				log.Printf("Error evaluating applicant %s for scheme %s, skipping them: %v", a.Id, s.Id, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+
+			// one more eligible applicant than the page holds means there is a next page
+			if len(eligibleApplicants) == q.Limit {
+				last := eligibleApplicants[len(eligibleApplicants)-1].Id
+				page.NextCursor = encodeCursor("id", last.String(), last)
+				return eligibleApplicants, page, nil
+			}
+			eligibleApplicants = append(eligibleApplicants, a)
+		}
+
+		if nextBatch == "" {
+			return eligibleApplicants, page, nil
+		}
+		batch.Cursor = nextBatch
+	}
 }
 
 func (s *Scheme) CheckSchemeExist(ctx context.Context, db *sql.DB) error {
@@ -268,7 +692,7 @@ func (s *Scheme) CheckSchemeExist(ctx context.Context, db *sql.DB) error {
 		return fmt.Errorf("error checking scheme existence: %v", err)
 	}
 	if !exists {
-		return fmt.Errorf("scheme %s does not exist", s.Id)
+		return schemeNotFound(s.Id)
 	}
 
 	return nil
@@ -282,8 +706,8 @@ func (s *Scheme) DeleteScheme(ctx context.Context, db *sql.DB) error {
 	}
 	defer tx.Rollback()
 
-	query := `UPDATE schemes SET deleted = $1, updated_at = $2 WHERE id = $3 AND deleted = false`
-	result, err := tx.ExecContext(ctx, query, true, time.Now(), s.Id)
+	query := `UPDATE schemes SET deleted = $1, updated_at = $2, version = version + 1 WHERE id = $3 AND deleted = false AND ($4 = 0 OR version = $4)`
+	result, err := tx.ExecContext(ctx, query, true, time.Now(), s.Id, s.Version)
 	if err != nil {
 		log.Println("Error updating scheme:", err)
 		return err
@@ -294,7 +718,11 @@ func (s *Scheme) DeleteScheme(ctx context.Context, db *sql.DB) error {
 		return fmt.Errorf("error checking rows affected: %v", err)
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("no scheme found with ID %s or scheme already deleted", s.Id)
+		return missedWrite(s.Id, s.Version, schemeNotFound(s.Id))
+	}
+
+	if err := s.retire(ctx, tx); err != nil {
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -305,6 +733,7 @@ func (s *Scheme) DeleteScheme(ctx context.Context, db *sql.DB) error {
 	return nil
 }
 
+// UpdateScheme changes the definition of the scheme and publishes it as a new version.
 func (s *Scheme) UpdateScheme(ctx context.Context, db *sql.DB, req SchemeRequest) error {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
@@ -313,65 +742,264 @@ func (s *Scheme) UpdateScheme(ctx context.Context, db *sql.DB, req SchemeRequest
 	}
 	defer tx.Rollback()
 
-	query := `UPDATE schemes SET name = $1, description = $2, updated_at = $3 WHERE id = $4 AND deleted = false`
-	_, err = tx.ExecContext(ctx, query, req.Name, req.Description, time.Now(), s.Id)
+	if err := s.update(ctx, tx, req, nil); err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// update writes the definition to the scheme rows and publishes it, from the draft when given.
+func (s *Scheme) update(ctx context.Context, tx *sql.Tx, req SchemeRequest, draft *uuid.UUID) error {
+	// keep the definition published until now before it is changed
+	if _, err := s.publishedVersion(ctx, tx); err != nil {
+		return err
+	}
+
+	rule, err := req.RuleValue()
+	if err != nil {
+		return err
+	}
+
+	var policy string
+	if err := tx.QueryRowContext(ctx, `SELECT application_policy FROM schemes WHERE id = $1`, s.Id).Scan(&policy); err != nil {
+		log.Println("Error querying application policy:", err)
+		return err
+	}
+
+	query := `UPDATE schemes SET name = $1, description = $2, application_policy = $3, effective_from = $4, effective_to = $5, application_from = $6, application_to = $7, budget = $8, max_recipients = $9, budget_policy = $10, rule = $11, updated_at = $12, version = version + 1 WHERE id = $13 AND deleted = false AND ($14 = 0 OR version = $14) RETURNING version`
+	err = tx.QueryRowContext(ctx, query, req.Name, req.Description, req.Policy(), req.EffectiveFrom, req.EffectiveTo, req.ApplicationFrom, req.ApplicationTo, req.Budget, req.MaxRecipients, req.FundingPolicy(), rule, time.Now(), s.Id, s.Version).Scan(&s.Version)
+	if err == sql.ErrNoRows {
+		return missedWrite(s.Id, s.Version, schemeNotFound(s.Id))
+	}
 	if err != nil {
-		log.Println("Error updating applicant:", err)
+		log.Println("Error updating scheme:", err)
 		return err
 	}
 
-	// Remove existing criteria and benefits for the scheme
-	deleteBenefitQuery := `UPDATE benefits SET deleted = $1, updated_at = $2 WHERE scheme_id = $3`
-	_, err = tx.ExecContext(ctx, deleteBenefitQuery, true, time.Now(), s.Id)
+	// the stored dedupe keys follow the policy, so applications are keyed again when it changes
+	if policy != req.Policy() {
+		if err := s.rekeyApplications(ctx, tx, req.Policy()); err != nil {
+			return err
+		}
+	}
+
+	// Criteria and benefits sent with their id are updated in place so applications keep pointing
+	// at them, the others are new. Existing rows left out of the request are removed.
+	keepCriteria, keepBenefits := req.ids()
+
+	deleteBenefitQuery := `UPDATE benefits SET deleted = $1, updated_at = $2 WHERE scheme_id = $3 AND deleted = false AND NOT (id = ANY($4))`
+	_, err = tx.ExecContext(ctx, deleteBenefitQuery, true, time.Now(), s.Id, pq.Array(keepBenefits))
 	if err != nil {
 		log.Println("Error deleting old benefits:", err)
 		return err
 	}
 
-	deleteCriteriaQuery := `UPDATE criteria SET deleted = $1, updated_at = $2 WHERE scheme_id = $3`
-	_, err = tx.ExecContext(ctx, deleteCriteriaQuery, true, time.Now(), s.Id)
+	deleteCriteriaQuery := `UPDATE criteria SET deleted = $1, updated_at = $2 WHERE scheme_id = $3 AND deleted = false AND NOT (id = ANY($4))`
+	_, err = tx.ExecContext(ctx, deleteCriteriaQuery, true, time.Now(), s.Id, pq.Array(keepCriteria))
 	if err != nil {
 		log.Println("Error deleting old criteria:", err)
 		return err
 	}
 
-	err = s.CreateCriteriaAndBenefit(ctx, tx, req)
-	if err != nil {
+	for i, criteria := range req.Criteria {
+		criteriaID, err := s.saveCriteria(ctx, tx, criteria)
+		if err != nil {
+			return unknownSchemeRow(err, fmt.Sprintf("criteria[%d].id", i))
+		}
+
+		for j, benefit := range criteria.Benefits {
+			if err := s.saveBenefit(ctx, tx, criteriaID, benefit); err != nil {
+				return unknownSchemeRow(err, fmt.Sprintf("criteria[%d].benefits[%d].id", i, j))
+			}
+		}
+	}
+
+	if err := s.saveRelationships(ctx, tx, req.Relationships); err != nil {
 		return err
 	}
 
-	err = tx.Commit()
+	return s.publish(ctx, tx, draft)
+}
+
+func (s *Scheme) CreateCriteriaAndBenefit(ctx context.Context, tx *sql.Tx, req SchemeRequest) error {
+	for _, criteria := range req.Criteria {
+		criteria.Id = nil
+		criteriaID, err := s.saveCriteria(ctx, tx, criteria)
+		if err != nil {
+			return err
+		}
+
+		// insert benefits
+		for _, benefit := range criteria.Benefits {
+			benefit.Id = nil
+			if err := s.saveBenefit(ctx, tx, criteriaID, benefit); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// errUnknownSchemeRow is returned by saveCriteria and saveBenefit for an id that is not a live row of the scheme.
+var errUnknownSchemeRow = errors.New("row does not belong to the scheme")
+
+// saveCriteria updates the criteria with the request id or inserts a new one, returning its id.
+func (s *Scheme) saveCriteria(ctx context.Context, tx *sql.Tx, criteria CriteriaRequest) (uuid.UUID, error) {
+	// each criteria is stored as a single rule so its conditions are evaluated together
+	criteriaValue, err := json.Marshal(criteria.Conditions)
 	if err != nil {
-		return fmt.Errorf("could not commit transaction: %v", err)
+		return uuid.Nil, fmt.Errorf("marshal criteria value failed: %v", err)
+	}
+
+	if criteria.Id == nil {
+		insertCriteria := `INSERT INTO criteria (scheme_id, criteria_key, criteria_value) VALUES ($1, $2, $3) RETURNING id`
+		var criteriaID uuid.UUID
+		err = tx.QueryRowContext(ctx, insertCriteria, s.Id, eligibility.RuleKey, bytes.ToLower(criteriaValue)).Scan(&criteriaID)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("could not insert criteria: %v", err)
+		}
+		return criteriaID, nil
+	}
+
+	updateCriteria := `UPDATE criteria SET criteria_key = $1, criteria_value = $2, updated_at = $3 WHERE id = $4 AND scheme_id = $5 AND deleted = false`
+	result, err := tx.ExecContext(ctx, updateCriteria, eligibility.RuleKey, bytes.ToLower(criteriaValue), time.Now(), *criteria.Id, s.Id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("could not update criteria: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("error checking rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return uuid.Nil, errUnknownSchemeRow
+	}
+	return *criteria.Id, nil
+}
+
+// saveBenefit updates the benefit with the request id, moving it under criteriaID, or inserts a new one.
+func (s *Scheme) saveBenefit(ctx context.Context, tx *sql.Tx, criteriaID uuid.UUID, benefit BenefitRequest) error {
+	schedule := benefit.Normalized()
+	if benefit.Id == nil {
+		insertBenefit := `INSERT INTO benefits (scheme_id, criteria_id, name, amount, frequency, instalments, start_rule, start_date) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+		if _, err := tx.ExecContext(ctx, insertBenefit, s.Id, criteriaID, benefit.Name, benefit.Amount, schedule.Frequency, schedule.Instalments, schedule.StartRule, schedule.StartDate); err != nil {
+			return fmt.Errorf("could not insert benefit: %v", err)
+		}
+		return nil
+	}
+
+	updateBenefit := `UPDATE benefits SET criteria_id = $1, name = $2, amount = $3, frequency = $4, instalments = $5, start_rule = $6, start_date = $7, updated_at = $8 WHERE id = $9 AND scheme_id = $10 AND deleted = false`
+	result, err := tx.ExecContext(ctx, updateBenefit, criteriaID, benefit.Name, benefit.Amount, schedule.Frequency, schedule.Instalments, schedule.StartRule, schedule.StartDate, time.Now(), *benefit.Id, s.Id)
+	if err != nil {
+		return fmt.Errorf("could not update benefit: %v", err)
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return errUnknownSchemeRow
+	}
 	return nil
 }
 
-func (s *Scheme) CreateCriteriaAndBenefit(ctx context.Context, tx *sql.Tx, req SchemeRequest) error {
+// unknownSchemeRow reports an id of the request that is not a row of the scheme as a field error at path.
+func unknownSchemeRow(err error, path string) error {
+	if !errors.Is(err, errUnknownSchemeRow) {
+		return err
+	}
+	return ValidationError(CodeValidationFailed, config.REQUEST_FAILED, FieldError{Field: path, Rule: RuleUnknownId})
+}
+
+// Scheme builds the unsaved scheme described by the request.
+func (req SchemeRequest) Scheme() Scheme {
+	scheme := Scheme{Name: req.Name, Description: req.Description, ApplicationPolicy: req.Policy(), SchemePeriod: req.SchemePeriod, SchemeFunding: req.SchemeFunding, Rule: req.Rule, Criteria: []SchemeCriteria{}, Relationships: req.Relationships}
 	for _, criteria := range req.Criteria {
-		for i, condition := range criteria.Conditions {
-			criteriaValue, err := json.Marshal(condition)
-			if err != nil {
-				return fmt.Errorf("marshal criteria value failed: %v", err)
+		benefits := []Benefit{}
+		for _, b := range criteria.Benefits {
+			benefit := b
+			benefits = append(benefits, Benefit{Name: &benefit.Name, Amount: &benefit.Amount, BenefitSchedule: benefit.Normalized()})
+		}
+
+		scheme.Criteria = append(scheme.Criteria, SchemeCriteria{Conditions: criteria.Conditions, Benefits: benefits})
+	}
+	return scheme
+}
+
+// Request returns the request that would recreate the scheme as it is, keeping the ids of its criteria
+// and benefits. It is the document a merge patch of the scheme is applied to.
+func (s *Scheme) Request() SchemeRequest {
+	req := SchemeRequest{Name: s.Name, Description: s.Description, ApplicationPolicy: s.ApplicationPolicy, SchemePeriod: s.SchemePeriod, SchemeFunding: s.SchemeFunding, Rule: s.Rule, Criteria: []CriteriaRequest{}, Relationships: s.Relationships}
+	for _, c := range s.Criteria {
+		criteria := CriteriaRequest{Id: &c.Id, Conditions: c.Conditions, Benefits: []BenefitRequest{}}
+		for _, b := range c.Benefits {
+			benefit := BenefitRequest{Id: &b.Id, BenefitSchedule: b.BenefitSchedule}
+			if b.Name != nil {
+				benefit.Name = *b.Name
 			}
-			insertCriteria := `INSERT INTO criteria (scheme_id, criteria_key, criteria_value) VALUES ($1, $2, $3) RETURNING id`
-			var criteriaID uuid.UUID
-			err = tx.QueryRowContext(ctx, insertCriteria, s.Id, i, bytes.ToLower(criteriaValue)).Scan(&criteriaID)
-			if err != nil {
-				return fmt.Errorf("could not insert criteria: %v", err)
+			if b.Amount != nil {
+				benefit.Amount = *b.Amount
 			}
+			criteria.Benefits = append(criteria.Benefits, benefit)
+		}
+		req.Criteria = append(req.Criteria, criteria)
+	}
+	return req
+}
 
-			// insert benefits
-			for _, benefit := range criteria.Benefits {
-				insertBenefit := `INSERT INTO benefits (scheme_id, criteria_id, name, amount) VALUES ($1, $2, $3, $4)`
-				_, err := tx.ExecContext(ctx, insertBenefit, s.Id, criteriaID, benefit.Name, benefit.Amount)
-				if err != nil {
-					return fmt.Errorf("could not insert benefit: %v", err)
-				}
+// ids returns the ids of the existing criteria and benefits kept by the request.
+func (req SchemeRequest) ids() (criteria []string, benefits []string) {
+	criteria, benefits = []string{}, []string{}
+	for _, c := range req.Criteria {
+		if c.Id != nil {
+			criteria = append(criteria, c.Id.String())
+		}
+		for _, b := range c.Benefits {
+			if b.Id != nil {
+				benefits = append(benefits, b.Id.String())
 			}
 		}
 	}
+	return criteria, benefits
+}
 
-	return nil
+// SchoolLevels returns the education levels referenced by the scheme rule and criteria.
+func (req SchemeRequest) SchoolLevels() []string {
+	names := []string{}
+	if req.Rule != nil {
+		names = append(names, eligibility.SchoolLevelNames(*req.Rule)...)
+	}
+	for _, criteria := range req.Criteria {
+		names = append(names, eligibility.SchoolLevelNames(criteria.Conditions)...)
+	}
+	return names
+}
+
+// Policy returns the application policy of the request, one active application by default.
+func (req SchemeRequest) Policy() string {
+	if req.ApplicationPolicy == "" {
+		return config.PolicyOneActive
+	}
+	return strings.ToLower(req.ApplicationPolicy)
+}
+
+// RuleValue returns the scheme rule as stored in the schemes.rule column.
+func (req SchemeRequest) RuleValue() (interface{}, error) {
+	if req.Rule == nil {
+		return nil, nil
+	}
+
+	rule, err := json.Marshal(req.Rule)
+	if err != nil {
+		return nil, fmt.Errorf("marshal scheme rule failed: %v", err)
+	}
+	return string(bytes.ToLower(rule)), nil
 }
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"math"
+	"oneCV/config"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// SchemeFunding caps what a scheme gives out. Budget is the total benefit amount its approved applications
+// may hold and MaxRecipients the number of applicants they may be for, an unset cap has no limit.
+// BudgetPolicy is what happens to an approval over a cap: it is blocked or the application is waitlisted.
+type SchemeFunding struct {
+	Budget        *float64 `json:"budget"`
+	MaxRecipients *int     `json:"max_recipients"`
+	BudgetPolicy  string   `json:"budget_policy"`
+}
+
+// FundingPolicy returns the budget policy, approvals over a cap are blocked by default.
+func (f SchemeFunding) FundingPolicy() string {
+	if f.BudgetPolicy == "" {
+		return config.BudgetBlock
+	}
+	return strings.ToLower(f.BudgetPolicy)
+}
+
+// SchemeFundingUsage is how much of the funding of a scheme its approved applications hold. An application
+// holds every instalment of the benefits it was approved for until it is cancelled, completing it keeps
+// them committed.
+type SchemeFundingUsage struct {
+	SchemeId      uuid.UUID `json:"scheme_id"`
+	Budget        *float64  `json:"budget"`
+	Committed     float64   `json:"committed"`
+	Remaining     *float64  `json:"remaining"`
+	MaxRecipients *int      `json:"max_recipients"`
+	Recipients    int       `json:"recipients"`
+	BudgetPolicy  string    `json:"budget_policy"`
+	Waitlisted    int       `json:"waitlisted"`
+}
+
+func (s *Scheme) GetFundingUsage(ctx context.Context, db *sql.DB) (SchemeFundingUsage, error) {
+	query := `SELECT s.budget, s.max_recipients, s.budget_policy, COALESCE(SUM(a.committed_amount), 0), COUNT(DISTINCT a.applicant_id) FILTER (WHERE a.committed_amount IS NOT NULL), COUNT(a.id) FILTER (WHERE a.status = $2) FROM schemes s LEFT JOIN applications a ON a.scheme_id = s.id AND (a.committed_amount IS NOT NULL OR a.status = $2) WHERE s.id = $1 AND s.deleted = false GROUP BY s.id`
+
+	usage := SchemeFundingUsage{SchemeId: s.Id}
+	err := db.QueryRowContext(ctx, query, s.Id, config.StatusWaitlisted).Scan(&usage.Budget, &usage.MaxRecipients, &usage.BudgetPolicy, &usage.Committed, &usage.Recipients, &usage.Waitlisted)
+	if err == sql.ErrNoRows {
+		return usage, schemeNotFound(s.Id)
+	}
+	if err != nil {
+		log.Println("Error getting scheme funding usage:", err)
+		return usage, err
+	}
+
+	if usage.Budget != nil {
+		remaining := math.Max(0, float64(cents(*usage.Budget)-cents(usage.Committed))/100)
+		usage.Remaining = &remaining
+	}
+	return usage, nil
+}
+
+// fundingClaim is what approving an application takes from the funding of its scheme.
+type fundingClaim struct {
+	SchemeFunding
+	SchemeId   uuid.UUID
+	Amount     float64
+	Committed  float64
+	Recipients int
+	// Recipient is set when the applicant already holds an approved application of the scheme
+	Recipient bool
+}
+
+// claimFunding works out what approving the application takes from the funding of the scheme. The scheme
+// row stays locked until the transaction ends, so concurrent approvals of a scheme are checked one after
+// another and each sees the amounts committed before it.
+func (ac *Application) claimFunding(ctx context.Context, tx *sql.Tx, schemeId uuid.UUID, applicantId uuid.UUID) (fundingClaim, error) {
+	claim := fundingClaim{SchemeId: schemeId}
+	err := tx.QueryRowContext(ctx, `SELECT budget, max_recipients, budget_policy FROM schemes WHERE id = $1 FOR UPDATE`, schemeId).Scan(&claim.Budget, &claim.MaxRecipients, &claim.BudgetPolicy)
+	if err != nil {
+		log.Println("Error locking scheme funding:", err)
+		return claim, err
+	}
+
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(benefit_amount * instalments), 0) FROM application_details WHERE application_id = $1`, ac.Id).Scan(&claim.Amount); err != nil {
+		log.Println("Error summing application benefits:", err)
+		return claim, err
+	}
+
+	query := `SELECT COALESCE(SUM(committed_amount), 0), COUNT(DISTINCT applicant_id), COALESCE(BOOL_OR(applicant_id = $3), false) FROM applications WHERE scheme_id = $1 AND id <> $2 AND committed_amount IS NOT NULL`
+	if err := tx.QueryRowContext(ctx, query, schemeId, ac.Id, applicantId).Scan(&claim.Committed, &claim.Recipients, &claim.Recipient); err != nil {
+		log.Println("Error getting committed scheme funding:", err)
+		return claim, err
+	}
+
+	return claim, nil
+}
+
+// fits reports whether the claim stays within the budget and recipient quota. Amounts are compared
+// in cents as they are stored.
+func (f fundingClaim) fits() bool {
+	if f.Budget != nil && cents(f.Committed)+cents(f.Amount) > cents(*f.Budget) {
+		return false
+	}
+	if f.MaxRecipients != nil && !f.Recipient && f.Recipients+1 > *f.MaxRecipients {
+		return false
+	}
+	return true
+}
+
+func (f fundingClaim) exceeded() *Error {
+	return ConflictError(CodeBudgetExceeded, config.SCHEME_BUDGET_EXCEEDED, map[string]interface{}{
+		"scheme_id":      f.SchemeId,
+		"amount":         f.Amount,
+		"budget":         f.Budget,
+		"committed":      f.Committed,
+		"max_recipients": f.MaxRecipients,
+		"recipients":     f.Recipients,
+	})
+}
+
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"oneCV/config"
+	"time"
+)
+
+// SchemePeriod is when a scheme is in effect and when it accepts applications. Dates are inclusive
+// YYYY-MM-DD and an unset bound is open. The application window defaults to the effective period.
+type SchemePeriod struct {
+	EffectiveFrom   *string `json:"effective_from"`
+	EffectiveTo     *string `json:"effective_to"`
+	ApplicationFrom *string `json:"application_from"`
+	ApplicationTo   *string `json:"application_to"`
+}
+
+// StateOn returns whether the scheme is upcoming, active or expired on the date.
+func (p SchemePeriod) StateOn(on time.Time) string {
+	date := on.Format("2006-01-02")
+	switch {
+	case p.EffectiveFrom != nil && date < *p.EffectiveFrom:
+		return config.SchemeUpcoming
+	case p.EffectiveTo != nil && date > *p.EffectiveTo:
+		return config.SchemeExpired
+	}
+	return config.SchemeActive
+}
+
+// EffectiveOn reports whether the scheme is in effect on the date, only schemes in effect are offered.
+func (p SchemePeriod) EffectiveOn(on time.Time) bool {
+	return p.StateOn(on) == config.SchemeActive
+}
+
+// AcceptsApplicationsOn reports whether an application submitted on the date is accepted.
+func (p SchemePeriod) AcceptsApplicationsOn(on time.Time) bool {
+	date := on.Format("2006-01-02")
+	from, to := p.ApplicationWindow()
+	return (from == nil || date >= *from) && (to == nil || date <= *to)
+}
+
+// ApplicationWindow returns the first and last day applications are accepted, nil when open.
+func (p SchemePeriod) ApplicationWindow() (from *string, to *string) {
+	from, to = p.ApplicationFrom, p.ApplicationTo
+	if from == nil {
+		from = p.EffectiveFrom
+	}
+	if to == nil {
+		to = p.EffectiveTo
+	}
+	return from, to
+}
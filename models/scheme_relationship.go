@@ -0,0 +1,238 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"oneCV/config"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// SchemeRelationship is a rule of a scheme about another scheme an applicant holds, that is has an
+// application approved for that was not cancelled. A scheme exclusive with another cannot be held with
+// it, one that requires another is only for its holders, and one that supersedes another replaces it so
+// its holders cannot apply for the other. The rules only gate applying: benefit amounts are not compared,
+// so there is no rule keeping only the highest of several benefits.
+type SchemeRelationship struct {
+	SchemeId uuid.UUID `json:"scheme_id"`
+	Relation string    `json:"relation"`
+}
+
+// RelationshipConflict is a relationship that stops an applicant applying for a scheme: the rule SchemeId
+// has about RelatedSchemeId, broken by the application held, if any.
+type RelationshipConflict struct {
+	SchemeId        uuid.UUID  `json:"scheme_id"`
+	Relation        string     `json:"relation"`
+	RelatedSchemeId uuid.UUID  `json:"related_scheme_id"`
+	ApplicationId   *uuid.UUID `json:"application_id"`
+}
+
+// relationshipRule is a relationship as stored, between two schemes that are not deleted.
+type relationshipRule struct {
+	SchemeId        uuid.UUID
+	RelatedSchemeId uuid.UUID
+	Relation        string
+}
+
+// message explaining a conflict of each relation
+var relationshipConflictMessages = map[string]string{
+	config.RelationExclusiveWith: config.SCHEME_EXCLUSIVE_HELD,
+	config.RelationRequires:      config.SCHEME_REQUIRED_NOT_HELD,
+	config.RelationSupersedes:    config.SCHEME_SUPERSEDED,
+}
+
+func relationshipConflict(conflicts []RelationshipConflict) *Error {
+	return ConflictError(CodeRelationshipConflict, relationshipConflictMessages[conflicts[0].Relation], map[string]interface{}{"conflicts": conflicts})
+}
+
+// fetchRelationshipRules returns the rules the schemes have about other schemes and the rules other
+// schemes have about them.
+func fetchRelationshipRules(ctx context.Context, db querier, schemeIds []string) ([]relationshipRule, error) {
+	query := `SELECT r.scheme_id, r.related_scheme_id, r.relation FROM scheme_relationships r INNER JOIN schemes s ON r.scheme_id = s.id AND s.deleted = false INNER JOIN schemes rs ON r.related_scheme_id = rs.id AND rs.deleted = false WHERE r.scheme_id = ANY($1) OR r.related_scheme_id = ANY($1) ORDER BY r.created_at, r.id`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(schemeIds))
+	if err != nil {
+		log.Println("Error querying scheme relationships:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []relationshipRule{}
+	for rows.Next() {
+		var rule relationshipRule
+		if err := rows.Scan(&rule.SchemeId, &rule.RelatedSchemeId, &rule.Relation); err != nil {
+			log.Println("Error scanning scheme relationship row:", err)
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// loadRelationships sets the relationships each of the schemes has about other schemes.
+func loadRelationships(ctx context.Context, db querier, schemes []Scheme) error {
+	if len(schemes) == 0 {
+		return nil
+	}
+
+	ids := []string{}
+	index := make(map[uuid.UUID]int)
+	for i, scheme := range schemes {
+		ids = append(ids, scheme.Id.String())
+		index[scheme.Id] = i
+	}
+
+	rules, err := fetchRelationshipRules(ctx, db, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		if i, ok := index[rule.SchemeId]; ok {
+			schemes[i].Relationships = append(schemes[i].Relationships, SchemeRelationship{SchemeId: rule.RelatedSchemeId, Relation: rule.Relation})
+		}
+	}
+	return nil
+}
+
+// heldSchemes returns the schemes the applicant holds with the latest application held for each.
+func heldSchemes(ctx context.Context, db querier, applicantId uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
+	query := `SELECT DISTINCT ON (scheme_id) scheme_id, id FROM applications WHERE applicant_id = $1 AND committed_amount IS NOT NULL ORDER BY scheme_id, submitted_at DESC`
+
+	rows, err := db.QueryContext(ctx, query, applicantId)
+	if err != nil {
+		log.Println("Error querying held applications:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	held := make(map[uuid.UUID]uuid.UUID)
+	for rows.Next() {
+		var schemeId, applicationId uuid.UUID
+		if err := rows.Scan(&schemeId, &applicationId); err != nil {
+			log.Println("Error scanning held application row:", err)
+			return nil, err
+		}
+		held[schemeId] = applicationId
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return nil, err
+	}
+
+	return held, nil
+}
+
+// RelationshipConflicts returns, for each of the schemes, the relationships that stop the applicant
+// applying for it given the schemes they hold. Schemes without conflicts are left out.
+func (a *Applicant) RelationshipConflicts(ctx context.Context, db querier, schemeIds []uuid.UUID) (map[uuid.UUID][]RelationshipConflict, error) {
+	conflicts := make(map[uuid.UUID][]RelationshipConflict)
+	if len(schemeIds) == 0 {
+		return conflicts, nil
+	}
+
+	ids := []string{}
+	for _, id := range schemeIds {
+		ids = append(ids, id.String())
+	}
+
+	rules, err := fetchRelationshipRules(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return conflicts, nil
+	}
+
+	held, err := heldSchemes(ctx, db, a.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range schemeIds {
+		if found := relationshipConflicts(id, rules, held); len(found) > 0 {
+			conflicts[id] = found
+		}
+	}
+	return conflicts, nil
+}
+
+// relationshipConflicts returns the rules that stop an applicant holding the held schemes from applying
+// for the scheme. Exclusivity applies whichever of the two schemes has the rule.
+func relationshipConflicts(schemeId uuid.UUID, rules []relationshipRule, held map[uuid.UUID]uuid.UUID) []RelationshipConflict {
+	conflicts := []RelationshipConflict{}
+	for _, rule := range rules {
+		var other uuid.UUID
+		var broken bool
+		switch schemeId {
+		case rule.SchemeId:
+			other = rule.RelatedSchemeId
+			_, holds := held[other]
+			broken = (rule.Relation == config.RelationExclusiveWith && holds) || (rule.Relation == config.RelationRequires && !holds)
+		case rule.RelatedSchemeId:
+			other = rule.SchemeId
+			_, holds := held[other]
+			broken = holds && (rule.Relation == config.RelationExclusiveWith || rule.Relation == config.RelationSupersedes)
+		}
+		if !broken {
+			continue
+		}
+
+		conflict := RelationshipConflict{SchemeId: rule.SchemeId, Relation: rule.Relation, RelatedSchemeId: rule.RelatedSchemeId}
+		if applicationId, ok := held[other]; ok {
+			conflict.ApplicationId = &applicationId
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts
+}
+
+// checkRelationships returns a conflict error when the applicant may not hold the scheme of the application.
+func (ac *Application) checkRelationships(ctx context.Context, db querier) error {
+	applicant := Applicant{Id: ac.ApplicantID}
+	conflicts, err := applicant.RelationshipConflicts(ctx, db, []uuid.UUID{ac.SchemeID})
+	if err != nil {
+		return err
+	}
+	if found := conflicts[ac.SchemeID]; len(found) > 0 {
+		return relationshipConflict(found)
+	}
+	return nil
+}
+
+// saveRelationships replaces the relationships of the scheme with the ones given. A relationship with
+// the scheme itself or with a scheme that does not exist is reported at its index.
+func (s *Scheme) saveRelationships(ctx context.Context, tx *sql.Tx, relationships []SchemeRelationship) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM scheme_relationships WHERE scheme_id = $1`, s.Id); err != nil {
+		log.Println("Error deleting scheme relationships:", err)
+		return err
+	}
+
+	insert := `INSERT INTO scheme_relationships (scheme_id, related_scheme_id, relation) SELECT $1, id, $3 FROM schemes WHERE id = $2 AND id <> $1 AND deleted = false`
+	for i, relationship := range relationships {
+		result, err := tx.ExecContext(ctx, insert, s.Id, relationship.SchemeId, strings.ToLower(relationship.Relation))
+		if err != nil {
+			return fmt.Errorf("could not insert scheme relationship: %v", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error checking rows affected: %v", err)
+		}
+		if rowsAffected == 0 {
+			return unknownSchemeRow(errUnknownSchemeRow, fmt.Sprintf("relationships[%d].scheme_id", i))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"oneCV/config"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SchemeVersion is a snapshot of a scheme definition. The criteria and benefits rows of a scheme always
+// hold its published version, every publish keeps the previous one as retired so applications can be
+// traced to the definition they were evaluated against. A draft is only a snapshot until it is published.
+type SchemeVersion struct {
+	Id          uuid.UUID     `json:"id"`
+	SchemeId    uuid.UUID     `json:"scheme_id"`
+	Number      *int          `json:"number"`
+	Status      string        `json:"status"`
+	Definition  SchemeRequest `json:"definition"`
+	PublishedAt *string       `json:"published_at"`
+	RetiredAt   *string       `json:"retired_at"`
+	CreatedAt   string        `json:"created_at"`
+	UpdatedAt   string        `json:"updated_at"`
+}
+
+// querier runs a query on the database or inside a transaction.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func schemeVersionNotFound(id uuid.UUID, number int) *Error {
+	return NotFoundError(CodeSchemeVersionNotFound, config.SCHEME_VERSION_NOT_FOUND, map[string]interface{}{"scheme_id": id, "number": number})
+}
+
+func schemeDraftNotFound(id uuid.UUID) *Error {
+	return NotFoundError(CodeSchemeDraftNotFound, config.SCHEME_DRAFT_NOT_FOUND, map[string]interface{}{"scheme_id": id})
+}
+
+// GetVersions returns the versions of the scheme, the draft first then the newest published.
+func (s *Scheme) GetVersions(ctx context.Context, db *sql.DB) ([]SchemeVersion, error) {
+	return s.fetchVersions(ctx, db, ` ORDER BY number DESC NULLS FIRST`)
+}
+
+func (s *Scheme) GetVersion(ctx context.Context, db *sql.DB, number int) (SchemeVersion, error) {
+	versions, err := s.fetchVersions(ctx, db, ` AND number = $2`, number)
+	if err != nil {
+		return SchemeVersion{}, err
+	}
+	if len(versions) == 0 {
+		return SchemeVersion{}, schemeVersionNotFound(s.Id, number)
+	}
+	return versions[0], nil
+}
+
+func (s *Scheme) GetDraft(ctx context.Context, db *sql.DB) (SchemeVersion, error) {
+	versions, err := s.fetchVersions(ctx, db, ` AND status = $2`, config.VersionDraft)
+	if err != nil {
+		return SchemeVersion{}, err
+	}
+	if len(versions) == 0 {
+		return SchemeVersion{}, schemeDraftNotFound(s.Id)
+	}
+	return versions[0], nil
+}
+
+func (s *Scheme) fetchVersions(ctx context.Context, db *sql.DB, clause string, args ...interface{}) ([]SchemeVersion, error) {
+	query := `SELECT id, scheme_id, number, status, definition, TO_CHAR(published_at, 'YYYY-MM-DD HH24:MI:SS'), TO_CHAR(retired_at, 'YYYY-MM-DD HH24:MI:SS'), TO_CHAR(created_at, 'YYYY-MM-DD HH24:MI:SS'), TO_CHAR(updated_at, 'YYYY-MM-DD HH24:MI:SS') FROM scheme_versions WHERE scheme_id = $1` + clause
+
+	rows, err := db.QueryContext(ctx, query, append([]interface{}{s.Id}, args...)...)
+	if err != nil {
+		log.Println("Error querying scheme versions:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []SchemeVersion{}
+	for rows.Next() {
+		var version SchemeVersion
+		var definition []byte
+		if err := rows.Scan(&version.Id, &version.SchemeId, &version.Number, &version.Status, &definition, &version.PublishedAt, &version.RetiredAt, &version.CreatedAt, &version.UpdatedAt); err != nil {
+			log.Println("Error scanning scheme version row:", err)
+			return nil, err
+		}
+		if err := json.Unmarshal(definition, &version.Definition); err != nil {
+			return nil, fmt.Errorf("invalid definition of scheme version %s: %v", version.Id, err)
+		}
+		versions = append(versions, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error iterating over rows:", err)
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// SaveDraft creates the draft of the scheme or replaces its definition. Criteria and benefits of the draft
+// sent with their id keep it when the draft is published. The draft is part of the scheme, so saving it
+// increments the scheme version.
+func (s *Scheme) SaveDraft(ctx context.Context, db *sql.DB, req SchemeRequest) error {
+	definition, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshal scheme definition failed: %v", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.bumpVersion(ctx, tx); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO scheme_versions (scheme_id, status, definition) VALUES ($1, $2, $3) ON CONFLICT (scheme_id) WHERE status = 'draft' DO UPDATE SET definition = EXCLUDED.definition, updated_at = $4`
+	if _, err := tx.ExecContext(ctx, query, s.Id, config.VersionDraft, definition, time.Now()); err != nil {
+		log.Println("Error saving scheme draft:", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+	return nil
+}
+
+// DeleteDraft discards the draft of the scheme, incrementing the scheme version.
+func (s *Scheme) DeleteDraft(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.bumpVersion(ctx, tx); err != nil {
+		return err
+	}
+
+	query := `DELETE FROM scheme_versions WHERE scheme_id = $1 AND status = $2`
+	result, err := tx.ExecContext(ctx, query, s.Id, config.VersionDraft)
+	if err != nil {
+		log.Println("Error deleting scheme draft:", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return schemeDraftNotFound(s.Id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+	return nil
+}
+
+// bumpVersion increments the scheme version for a write to its draft, while the scheme is still at
+// the version it was read at.
+func (s *Scheme) bumpVersion(ctx context.Context, tx *sql.Tx) error {
+	query := `UPDATE schemes SET version = version + 1 WHERE id = $1 AND deleted = false AND ($2 = 0 OR version = $2) RETURNING version`
+	err := tx.QueryRowContext(ctx, query, s.Id, s.Version).Scan(&s.Version)
+	if err == sql.ErrNoRows {
+		return missedWrite(s.Id, s.Version, schemeNotFound(s.Id))
+	}
+	if err != nil {
+		log.Println("Error updating scheme version:", err)
+		return err
+	}
+	return nil
+}
+
+// PublishDraft makes the draft the definition of the scheme, retiring the version published until now.
+func (s *Scheme) PublishDraft(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var draftId uuid.UUID
+	var definition []byte
+	err = tx.QueryRowContext(ctx, `SELECT id, definition FROM scheme_versions WHERE scheme_id = $1 AND status = $2 FOR UPDATE`, s.Id, config.VersionDraft).Scan(&draftId, &definition)
+	if err == sql.ErrNoRows {
+		return schemeDraftNotFound(s.Id)
+	}
+	if err != nil {
+		log.Println("Error getting scheme draft:", err)
+		return err
+	}
+
+	var req SchemeRequest
+	if err := json.Unmarshal(definition, &req); err != nil {
+		return fmt.Errorf("invalid definition of scheme version %s: %v", draftId, err)
+	}
+
+	if err := s.update(ctx, tx, req, &draftId); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %v", err)
+	}
+	return nil
+}
+
+// publishedVersion returns the id of the published version of the scheme. Schemes created before
+// versioning have none, their current definition is published as version 1 the first time it is needed.
+func (s *Scheme) publishedVersion(ctx context.Context, tx *sql.Tx) (uuid.UUID, error) {
+	if err := s.lock(ctx, tx); err != nil {
+		return uuid.Nil, err
+	}
+
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, `SELECT id FROM scheme_versions WHERE scheme_id = $1 AND status = $2`, s.Id, config.VersionPublished).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		log.Println("Error getting published scheme version:", err)
+		return uuid.Nil, err
+	}
+
+	if err := s.publish(ctx, tx, nil); err != nil {
+		return uuid.Nil, err
+	}
+	return *s.VersionId, nil
+}
+
+// publish snapshots the criteria and benefits rows of the scheme as its new published version, numbered
+// after the latest one. The draft is published in place when given, otherwise a new version is added.
+func (s *Scheme) publish(ctx context.Context, tx *sql.Tx, draft *uuid.UUID) error {
+	if err := s.lock(ctx, tx); err != nil {
+		return err
+	}
+
+	schemes, err := s.FetchSchemes(ctx, tx, ` AND s.id = $1`, s.Id)
+	if err != nil {
+		return err
+	}
+	if len(schemes) == 0 {
+		return schemeNotFound(s.Id)
+	}
+
+	definition, err := json.Marshal(schemes[0].Request())
+	if err != nil {
+		return fmt.Errorf("marshal scheme definition failed: %v", err)
+	}
+
+	now := time.Now()
+	retireQuery := `UPDATE scheme_versions SET status = $1, retired_at = $2, updated_at = $2 WHERE scheme_id = $3 AND status = $4`
+	if _, err := tx.ExecContext(ctx, retireQuery, config.VersionRetired, now, s.Id, config.VersionPublished); err != nil {
+		log.Println("Error retiring scheme version:", err)
+		return err
+	}
+
+	var number int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(number), 0) + 1 FROM scheme_versions WHERE scheme_id = $1`, s.Id).Scan(&number); err != nil {
+		log.Println("Error numbering scheme version:", err)
+		return err
+	}
+
+	var id uuid.UUID
+	if draft == nil {
+		query := `INSERT INTO scheme_versions (scheme_id, number, status, definition, published_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+		err = tx.QueryRowContext(ctx, query, s.Id, number, config.VersionPublished, definition, now).Scan(&id)
+	} else {
+		query := `UPDATE scheme_versions SET number = $1, status = $2, definition = $3, published_at = $4, updated_at = $4 WHERE id = $5 RETURNING id`
+		err = tx.QueryRowContext(ctx, query, number, config.VersionPublished, definition, now, *draft).Scan(&id)
+	}
+	if err != nil {
+		log.Println("Error publishing scheme version:", err)
+		return err
+	}
+
+	s.VersionId, s.VersionNumber = &id, &number
+	return nil
+}
+
+// lock locks the scheme row until the transaction ends, so versions of a scheme are published and
+// numbered one after another.
+func (s *Scheme) lock(ctx context.Context, tx *sql.Tx) error {
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, `SELECT id FROM schemes WHERE id = $1 FOR UPDATE`, s.Id).Scan(&id)
+	if err == sql.ErrNoRows {
+		return schemeNotFound(s.Id)
+	}
+	if err != nil {
+		log.Println("Error locking scheme:", err)
+		return err
+	}
+	return nil
+}
+
+// retire retires the published version of a deleted scheme and drops its draft.
+func (s *Scheme) retire(ctx context.Context, tx *sql.Tx) error {
+	query := `UPDATE scheme_versions SET status = $1, retired_at = $2, updated_at = $2 WHERE scheme_id = $3 AND status = $4`
+	if _, err := tx.ExecContext(ctx, query, config.VersionRetired, time.Now(), s.Id, config.VersionPublished); err != nil {
+		log.Println("Error retiring scheme version:", err)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM scheme_versions WHERE scheme_id = $1 AND status = $2`, s.Id, config.VersionDraft); err != nil {
+		log.Println("Error deleting scheme draft:", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"oneCV/config"
+
+	"github.com/google/uuid"
+)
+
+// Applicants, schemes and applications carry a version that every write increments. A write made
+// with a non-zero Version on the model only applies while the record is still at that version,
+// zero writes whatever the current version is.
+
+func versionConflict(id uuid.UUID) *Error {
+	return PreconditionFailedError(CodePreconditionFailed, config.PRECONDITION_FAILED, map[string]interface{}{"id": id})
+}
+
+// CheckVersion returns a precondition failed error when the expected version is set and is not the current one.
+func CheckVersion(id uuid.UUID, current int, expected int) error {
+	if expected != 0 && expected != current {
+		return versionConflict(id)
+	}
+	return nil
+}
+
+// missedWrite is the error for a write that matched no row, the record changed when the write was
+// conditional on its version, otherwise it no longer exists.
+func missedWrite(id uuid.UUID, version int, notFound *Error) *Error {
+	if version != 0 {
+		return versionConflict(id)
+	}
+	return notFound
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"database/sql"
 	"oneCV/controllers"
+	"oneCV/validator"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,25 +13,71 @@ func InitRoutes(router *gin.Engine, db *sql.DB) {
 	applicantController := &controllers.ApplicantController{DB: db}
 	applicantionController := &controllers.ApplicantionController{DB: db}
 	schemeController := &controllers.SchemeController{DB: db}
+	educationLevelController := &controllers.EducationLevelController{DB: db}
+	eligibilityController := &controllers.EligibilityController{DB: db}
+	ledgerController := &controllers.LedgerController{DB: db}
+
+	validator.UseJSONFieldNames()
 
 	api := router.Group("/api")
+	api.Use(controllers.Localize())
 	// Applicant routes
 	api.GET("/applicants", applicantController.GetAllApplicants)
 	api.POST("/applicants", applicantController.CreateApplicant)
 	api.GET("/applicants/:id", applicantController.GetApplicantByID)
 	api.PUT("/applicants/:id", applicantController.UpdateApplicant)
+	api.PATCH("/applicants/:id", applicantController.PatchApplicant)
 	api.DELETE("/applicants/:id", applicantController.DeleteApplicant)
+	api.GET("/applicants/:id/household", applicantController.GetHouseholdMembers)
+	api.POST("/applicants/:id/household", applicantController.AddHouseholdMember)
+	api.PUT("/applicants/:id/household/:memberId", applicantController.UpdateHouseholdMember)
+	api.DELETE("/applicants/:id/household/:memberId", applicantController.DeleteHouseholdMember)
 
 	// Scheme routes
 	api.GET("/schemes", schemeController.GetAllSchemes)
 	api.POST("/schemes", schemeController.CreateScheme)
 	api.GET("/schemes/eligible", schemeController.GetEligibleSchemes)
+	api.GET("/schemes/:id/eligibility", schemeController.GetSchemeEligibility)
+	api.GET("/schemes/:id/eligible-applicants", schemeController.GetEligibleApplicants)
+	api.POST("/schemes/:id/preview", schemeController.PreviewSchemeImpact)
+	api.GET("/schemes/:id", schemeController.GetSchemeByID)
+	api.GET("/schemes/:id/funding", schemeController.GetSchemeFunding)
+	api.GET("/schemes/:id/versions", schemeController.GetSchemeVersions)
+	api.GET("/schemes/:id/versions/:number", schemeController.GetSchemeVersion)
+	api.PUT("/schemes/:id/draft", schemeController.SaveSchemeDraft)
+	api.DELETE("/schemes/:id/draft", schemeController.DeleteSchemeDraft)
+	api.POST("/schemes/:id/draft/publish", schemeController.PublishSchemeDraft)
 	api.PUT("/schemes/:id", schemeController.UpdateScheme)
+	api.PATCH("/schemes/:id", schemeController.PatchScheme)
 	api.DELETE("/schemes/:id", schemeController.DeleteScheme)
 
+	// Eligibility routes
+	api.POST("/eligibility/simulate", eligibilityController.SimulateEligibility)
+
 	// Application routes
 	api.GET("/applications", applicantionController.GetAllApplications)
 	api.POST("/applications", applicantionController.CreateApplication)
+	api.GET("/applications/:id", applicantionController.GetApplicationByID)
 	api.PUT("/applications/:id", applicantionController.UpdateApplication)
+	api.GET("/applications/:id/transitions", applicantionController.GetApplicationTransitions)
+	api.GET("/applications/:id/history", applicantionController.GetApplicationHistory)
+	api.GET("/applications/:id/disbursements", applicantionController.GetDisbursements)
+	api.POST("/applications/:id/disbursements/pause", applicantionController.PauseDisbursements)
+	api.POST("/applications/:id/disbursements/resume", applicantionController.ResumeDisbursements)
+	api.POST("/applications/:id/disbursements/cancel", applicantionController.CancelDisbursements)
+	api.GET("/applications/:id/ledger", ledgerController.GetApplicationLedger)
 	api.DELETE("/applications/:id", applicantionController.DeleteApplication)
+
+	// Ledger routes
+	api.POST("/ledger/entries", ledgerController.PostPayout)
+	api.GET("/ledger/entries/:id", ledgerController.GetLedgerEntry)
+	api.POST("/ledger/entries/:id/settle", ledgerController.SettlePayout)
+	api.POST("/ledger/entries/:id/reverse", ledgerController.ReversePayout)
+	api.GET("/ledger/reconciliation", ledgerController.ExportReconciliation)
+
+	// Education level routes
+	api.GET("/education-levels", educationLevelController.GetAllEducationLevels)
+	api.POST("/education-levels", educationLevelController.CreateEducationLevel)
+	api.PUT("/education-levels/:id", educationLevelController.UpdateEducationLevel)
+	api.DELETE("/education-levels/:id", educationLevelController.DeleteEducationLevel)
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
 )
 
@@ -20,21 +21,58 @@ func DateFormat(date string, format string) string {
 }
 
 func CalculateAge(date string) (int, error) {
+	return CalculateAgeOn(date, time.Now())
+}
+
+// CalculateAgeOn returns the age in completed years on the given date.
+func CalculateAgeOn(date string, on time.Time) (int, error) {
 	birthDate, err := time.Parse("2006-01-02", date)
 	if err != nil {
 		return 0, fmt.Errorf("invalid date format: %v", err)
 	}
 
-	currentDate := time.Now()
-	age := currentDate.Year() - birthDate.Year()
-	if currentDate.YearDay() < birthDate.YearDay() {
+	age := on.Year() - birthDate.Year()
+	// compare month and day rather than YearDay, which shifts by one after Feb in leap years
+	if on.Month() < birthDate.Month() || (on.Month() == birthDate.Month() && on.Day() < birthDate.Day()) {
 		age--
 	}
 
 	return age, nil
 }
 
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ParseLimit reads the limit query value of a cursor paginated list, falling back to DefaultPageSize.
+func ParseLimit(limit string) (int, error) {
+	if limit == "" {
+		return DefaultPageSize, nil
+	}
+
+	size, err := strconv.Atoi(limit)
+	if err != nil || size < 1 || size > MaxPageSize {
+		return 0, fmt.Errorf("invalid limit: %s", limit)
+	}
+	return size, nil
+}
+
 func IsJson(str string) bool {
 	var js map[string]interface{}
 	return json.Unmarshal([]byte(str), &js) == nil
 }
+
+func StringValue(str *string) string {
+	if str == nil {
+		return ""
+	}
+	return *str
+}
+
+func FloatValue(num *float64) float64 {
+	if num == nil {
+		return 0
+	}
+	return *num
+}
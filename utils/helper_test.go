@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalculateAgeOn(t *testing.T) {
+	tests := []struct {
+		name      string
+		birthDate string
+		on        string
+		want      int
+	}{
+		{"day before birthday", "1990-06-15", "2024-06-14", 33},
+		{"on birthday", "1990-06-15", "2024-06-15", 34},
+		{"day after birthday", "1990-06-15", "2024-06-16", 34},
+		{"earlier month", "1990-06-15", "2024-05-20", 33},
+		{"later month", "1990-06-15", "2024-07-01", 34},
+		{"born on the day", "2024-06-15", "2024-06-15", 0},
+		{"after February in a leap year", "2000-03-01", "2024-02-29", 23},
+		{"on 1 March in a leap year", "2000-03-01", "2024-03-01", 24},
+		{"leap day birthday, 28 February of a non-leap year", "2004-02-29", "2023-02-28", 18},
+		{"leap day birthday, 1 March of a non-leap year", "2004-02-29", "2023-03-01", 19},
+		{"leap day birthday, on 29 February", "2004-02-29", "2024-02-29", 20},
+		{"leap day birthday, 28 February of a leap year", "2004-02-29", "2024-02-28", 19},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			on, err := time.Parse("2006-01-02", tt.on)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := CalculateAgeOn(tt.birthDate, on)
+			if err != nil {
+				t.Fatalf("CalculateAgeOn(%q, %s) returned error: %v", tt.birthDate, tt.on, err)
+			}
+			if got != tt.want {
+				t.Errorf("CalculateAgeOn(%q, %s) = %d, want %d", tt.birthDate, tt.on, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateAgeOnInvalidDate(t *testing.T) {
+	for _, date := range []string{"", "15-06-1990", "1990-02-30", "not a date"} {
+		if _, err := CalculateAgeOn(date, time.Now()); err == nil {
+			t.Errorf("CalculateAgeOn(%q) returned no error", date)
+		}
+	}
+}
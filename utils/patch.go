@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrPatchNotObject = errors.New("merge patch must be a JSON object")
+
+// MergePatch applies a JSON merge patch (RFC 7396) to the target document. Members of the patch
+// replace those of the target, objects are merged recursively, null removes a member and arrays
+// are replaced as a whole. Only object patches are accepted, the API never replaces a whole resource.
+func MergePatch(target []byte, patch []byte) ([]byte, error) {
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %v", err)
+	}
+	if _, ok := patchValue.(map[string]interface{}); !ok {
+		return nil, ErrPatchNotObject
+	}
+
+	var targetValue interface{}
+	if err := json.Unmarshal(target, &targetValue); err != nil {
+		return nil, fmt.Errorf("invalid merge patch target: %v", err)
+	}
+
+	return json.Marshal(mergePatch(targetValue, patchValue))
+}
+
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
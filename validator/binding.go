@@ -0,0 +1,58 @@
+package validator
+
+import (
+	"encoding/json"
+	"errors"
+	"oneCV/models"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	playground "github.com/go-playground/validator/v10"
+)
+
+// UseJSONFieldNames makes binding errors name fields by their json tag, e.g. household[0].name.
+func UseJSONFieldNames() {
+	engine, ok := binding.Validator.Engine().(*playground.Validate)
+	if !ok {
+		return
+	}
+
+	engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+}
+
+// BindingErrors converts the error of a failed ShouldBind into field errors.
+// It returns nil when the body could not be read at all, e.g. malformed JSON.
+func BindingErrors(err error) []models.FieldError {
+	var validationErrors playground.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		errs := []models.FieldError{}
+		for _, fe := range validationErrors {
+			// the namespace starts with the struct name, e.g. Applicant.name
+			field := fe.Namespace()
+			if i := strings.Index(field, "."); i >= 0 {
+				field = field[i+1:]
+			}
+
+			if fe.Tag() == RuleRequired {
+				errs = append(errs, fieldError(field, RuleRequired, nil))
+			} else {
+				errs = append(errs, fieldError(field, fe.Tag(), fe.Value()))
+			}
+		}
+		return errs
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		return []models.FieldError{fieldError(typeError.Field, RuleType, typeError.Value)}
+	}
+
+	return nil
+}
@@ -1,21 +1,74 @@
 package validator
 
 import (
+	"fmt"
 	"log"
 	"oneCV/config"
+	"oneCV/eligibility"
 	"oneCV/models"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-func ValidateApplicantForm(applicant models.Applicant) bool {
-	if len(applicant.HouseholdMembers) > 0 {
-		if validate := ValidateHouseholdMembers(applicant.HouseholdMembers); !validate {
-			return false
-		}
+// rules reported in field errors
+const (
+	RuleRequired    = "required"
+	RuleOneOf       = "one_of"
+	RuleDate        = "date"
+	RuleNonNegative = "non_negative"
+	RulePositive    = "positive"
+	RuleRange       = "range"
+	RuleNotEmpty    = "not_empty"
+	RuleUnknownKey  = "unknown_key"
+	RuleType        = "type"
+	RuleFormat      = "format"
+	RuleWithin      = "within"
+	RuleUnique      = "unique"
+)
+
+func fieldError(field string, rule string, value interface{}) models.FieldError {
+	return models.FieldError{Field: field, Rule: rule, Value: value}
+}
+
+// join builds the JSON path of a nested field, e.g. join("household[0]", "name")
+func join(parent string, field string) string {
+	if parent == "" {
+		return field
 	}
-	return applicant.DateOfBirth != "" && ValidateEmploymentStatus(applicant.EmploymentStatus) && ValidateMaritalStatus(applicant.MaritalStatus) && ValidateSex(applicant.Sex)
+	return parent + "." + field
+}
+
+func index(parent string, i int) string {
+	return fmt.Sprintf("%s[%d]", parent, i)
+}
+
+func ValidateApplicantForm(applicant models.Applicant) []models.FieldError {
+	errs := []models.FieldError{}
+	if strings.TrimSpace(applicant.Name) == "" {
+		errs = append(errs, fieldError("name", RuleRequired, nil))
+	}
+	if !ValidateEmploymentStatus(applicant.EmploymentStatus) {
+		errs = append(errs, fieldError("employment_status", RuleOneOf, applicant.EmploymentStatus))
+	}
+	if !ValidateSex(applicant.Sex) {
+		errs = append(errs, fieldError("sex", RuleOneOf, applicant.Sex))
+	}
+	if applicant.DateOfBirth == "" {
+		errs = append(errs, fieldError("date_of_birth", RuleRequired, nil))
+	} else if !ValidateDate(applicant.DateOfBirth) {
+		errs = append(errs, fieldError("date_of_birth", RuleDate, applicant.DateOfBirth))
+	}
+	if !ValidateMaritalStatus(applicant.MaritalStatus) {
+		errs = append(errs, fieldError("marital_status", RuleOneOf, applicant.MaritalStatus))
+	}
+	if !ValidateIncome(applicant.MonthlyIncome) {
+		errs = append(errs, fieldError("monthly_income", RuleNonNegative, *applicant.MonthlyIncome))
+	}
+
+	return append(errs, ValidateHouseholdMembers(applicant.HouseholdMembers)...)
 }
 
 func ValidateEmploymentStatus(status string) bool {
@@ -37,25 +90,163 @@ func ValidateSex(sex string) bool {
 	return Validator(sex, validSexes)
 }
 
+func ValidateDate(date string) bool {
+	_, err := time.Parse("2006-01-02", date)
+	return err == nil
+}
+
+// school levels are configured in education_levels, so only the format is checked here
 func ValidateSchool(school string) bool {
-	validSchool := []string{"== primary", "== secondary"}
-	return Validator(school, validSchool)
+	if _, err := eligibility.ParseSchoolLevel(school); err != nil {
+		log.Printf("Input validate failed => %v", err)
+		return false
+	}
+	return true
+}
+
+func ValidateAgeBasis(basis interface{}) bool {
+	if value, ok := basis.(string); ok && eligibility.IsAgeBasis(value) {
+		return true
+	}
+
+	log.Printf("Input validate failed => age_basis : %+v", basis)
+	return false
+}
+
+func ValidateEducationLevelForm(level models.EducationLevel) []models.FieldError {
+	errs := []models.FieldError{}
+	if strings.TrimSpace(level.Name) == "" {
+		errs = append(errs, fieldError("name", RuleRequired, nil))
+	}
+
+	if !Validator(level.BandType, []string{eligibility.BandAge, eligibility.BandBirthYear}) {
+		errs = append(errs, fieldError("band_type", RuleOneOf, level.BandType))
+	}
+
+	// at least one bound is required
+	if level.Min == nil && level.Max == nil {
+		errs = append(errs, fieldError("min", RuleRequired, nil))
+	}
+
+	if level.Min != nil && level.Max != nil && *level.Min > *level.Max {
+		errs = append(errs, fieldError("max", RuleRange, *level.Max))
+	}
+
+	return errs
 }
 
 func ValidateApplicationStatus(status string) bool {
-	validStatus := []string{config.StatusPending, config.StatusRejected, config.StatusApproved, config.StatusInProgress, config.StatusCompleted, config.StatusOnHold, config.StatusCancelled}
+	validStatus := []string{config.StatusPending, config.StatusRejected, config.StatusApproved, config.StatusInProgress, config.StatusCompleted, config.StatusOnHold, config.StatusCancelled, config.StatusWaitlisted}
 	return Validator(status, validStatus)
 }
 
-func ValidateHouseholdMembers(members []models.HouseholdMember) bool {
-	for _, v := range members {
-		if v.DateOfBirth == nil || v.Name == nil || v.Relation == nil {
-			log.Printf("Household member form validate failed : %+v", v)
-			return false
-		}
+func ValidateIncome(income *float64) bool {
+	return income == nil || *income >= 0
+}
+
+func ValidateApplicationUpdateForm(application models.ApplicationUpdateRequest) []models.FieldError {
+	errs := []models.FieldError{}
+	if !ValidateApplicationStatus(application.Status) {
+		errs = append(errs, fieldError("status", RuleOneOf, application.Status))
 	}
+	return errs
+}
 
-	return true
+// ValidatePayoutForm checks a payout posted to the ledger, a payout is sent (scheduled) or already settled.
+func ValidatePayoutForm(payout models.PayoutRequest) []models.FieldError {
+	errs := []models.FieldError{}
+	if payout.DisbursementId == uuid.Nil {
+		errs = append(errs, fieldError("disbursement_id", RuleRequired, nil))
+	}
+	if !Validator(payout.Status, []string{config.LedgerScheduled, config.LedgerPaid, config.LedgerFailed}) {
+		errs = append(errs, fieldError("status", RuleOneOf, payout.Status))
+	}
+	return append(errs, validateLedgerEntry(payout.Date, payout.Reference, true)...)
+}
+
+func ValidateSettleForm(settle models.SettleRequest) []models.FieldError {
+	errs := []models.FieldError{}
+	if !Validator(settle.Status, []string{config.LedgerPaid, config.LedgerFailed}) {
+		errs = append(errs, fieldError("status", RuleOneOf, settle.Status))
+	}
+	return append(errs, validateLedgerEntry(settle.Date, settle.Reference, false)...)
+}
+
+func ValidateReversalForm(reversal models.ReversalRequest) []models.FieldError {
+	return validateLedgerEntry(reversal.Date, reversal.Reference, true)
+}
+
+// validateLedgerEntry checks the optional date of a ledger entry and its bank or batch reference.
+func validateLedgerEntry(date string, reference string, referenceRequired bool) []models.FieldError {
+	errs := []models.FieldError{}
+	if date != "" && !ValidateDate(date) {
+		errs = append(errs, fieldError("date", RuleDate, date))
+	}
+	if referenceRequired && strings.TrimSpace(reference) == "" {
+		errs = append(errs, fieldError("reference", RuleRequired, nil))
+	}
+	return errs
+}
+
+func ValidateApplicationPolicy(policy string) bool {
+	validPolicies := []string{config.PolicyOneActive, config.PolicyOnePerYear, config.PolicyUnlimited}
+	return Validator(policy, validPolicies)
+}
+
+func ValidateRelation(relation string) bool {
+	validRelations := []string{config.RelationExclusiveWith, config.RelationRequires, config.RelationSupersedes}
+	return Validator(relation, validRelations)
+}
+
+func ValidateBudgetPolicy(policy string) bool {
+	validPolicies := []string{config.BudgetBlock, config.BudgetWaitlist}
+	return Validator(policy, validPolicies)
+}
+
+func ValidateFrequency(frequency string) bool {
+	validFrequencies := []string{config.FrequencyOneOff, config.FrequencyMonthly, config.FrequencyQuarterly, config.FrequencyYearly}
+	return Validator(frequency, validFrequencies)
+}
+
+func ValidateStartRule(rule string) bool {
+	validRules := []string{config.StartOnApproval, config.StartNextMonth, config.StartFixedDate}
+	return Validator(rule, validRules)
+}
+
+func ValidateHouseholdMembers(members []models.HouseholdMember) []models.FieldError {
+	errs := []models.FieldError{}
+	for i, v := range members {
+		errs = append(errs, ValidateHouseholdMember(index("household", i), v)...)
+	}
+
+	return errs
+}
+
+// ValidateHouseholdMember checks a single member, path is its JSON path in the request or empty for a member on its own.
+func ValidateHouseholdMember(path string, v models.HouseholdMember) []models.FieldError {
+	errs := []models.FieldError{}
+	if v.Name == nil || strings.TrimSpace(*v.Name) == "" {
+		errs = append(errs, fieldError(join(path, "name"), RuleRequired, nil))
+	}
+	if v.Relation == nil || strings.TrimSpace(*v.Relation) == "" {
+		errs = append(errs, fieldError(join(path, "relation"), RuleRequired, nil))
+	}
+	if v.DateOfBirth == nil {
+		errs = append(errs, fieldError(join(path, "date_of_birth"), RuleRequired, nil))
+	} else if !ValidateDate(*v.DateOfBirth) {
+		errs = append(errs, fieldError(join(path, "date_of_birth"), RuleDate, *v.DateOfBirth))
+	}
+	if v.EmploymentStatus != nil && !ValidateEmploymentStatus(*v.EmploymentStatus) {
+		errs = append(errs, fieldError(join(path, "employment_status"), RuleOneOf, *v.EmploymentStatus))
+	}
+	if v.Sex != nil && !ValidateSex(*v.Sex) {
+		errs = append(errs, fieldError(join(path, "sex"), RuleOneOf, *v.Sex))
+	}
+	if !ValidateIncome(v.MonthlyIncome) {
+		errs = append(errs, fieldError(join(path, "monthly_income"), RuleNonNegative, *v.MonthlyIncome))
+	}
+
+	return errs
 }
 
 func Validator(value string, values []string) bool {
@@ -69,84 +260,247 @@ func Validator(value string, values []string) bool {
 	return false
 }
 
-func ValidateSchemeForm(scheme models.SchemeRequest) bool {
-	if scheme.Name == "" {
-		log.Printf("Scheme name is required")
-		return false
+func ValidateSchemeForm(scheme models.SchemeRequest) []models.FieldError {
+	errs := []models.FieldError{}
+	if strings.TrimSpace(scheme.Name) == "" {
+		errs = append(errs, fieldError("name", RuleRequired, nil))
 	}
 
 	if len(scheme.Criteria) == 0 {
-		log.Printf("At least one criteria is required")
-		return false
+		errs = append(errs, fieldError("criteria", RuleRequired, nil))
 	}
 
-	validCriteriaKeys := map[string]bool{
-		"employment_status": true,
-		"has_children":      true,
-		"marital_status":    true,
-		"sex":               true,
+	if scheme.ApplicationPolicy != "" && !ValidateApplicationPolicy(scheme.ApplicationPolicy) {
+		errs = append(errs, fieldError("application_policy", RuleOneOf, scheme.ApplicationPolicy))
 	}
 
-	for _, v := range scheme.Criteria {
-		for key := range v.Conditions {
-			if !validCriteriaKeys[key] {
-				log.Printf("Invalid criteria key: %s", key)
-				return false
-			}
+	errs = append(errs, ValidateSchemePeriod(scheme.SchemePeriod)...)
+	errs = append(errs, ValidateSchemeFunding(scheme.SchemeFunding)...)
+	errs = append(errs, ValidateSchemeRelationships(scheme.Relationships)...)
 
-			switch key {
-			case "employment_status":
-				if employmentStatus, ok := v.Conditions[key].(string); ok {
-					if !ValidateEmploymentStatus(employmentStatus) {
-						log.Printf("Invalid employment status")
-						return false
-					}
-				}
-			case "has_children":
-				if hasChildren, ok := v.Conditions[key].(map[string]interface{}); ok {
-					// only recognize school_level for now
-					if schoolLevel, ok := hasChildren["school_level"].(string); ok {
-						if !ValidateSchool(schoolLevel) {
-							log.Printf("Invalid school")
-							return false
-						}
-					} else {
-						log.Printf("Invalid type for school_level: %+v", hasChildren)
-						return false
-					}
-				}
-
-			case "marital_status":
-				if maritalStatus, ok := v.Conditions[key].(string); ok {
-					if !ValidateMaritalStatus(maritalStatus) {
-						log.Printf("Invalid marital status")
-						return false
-					}
-				}
-			case "sex":
-				if sex, ok := v.Conditions[key].(string); ok {
-					if !ValidateSex(sex) {
-						log.Printf("Invalid sex")
-						return false
-					}
-				}
+	if scheme.Rule != nil {
+		errs = append(errs, ValidateRule("rule", *scheme.Rule)...)
+	}
+
+	for i, v := range scheme.Criteria {
+		path := index("criteria", i)
+		errs = append(errs, ValidateRule(join(path, "conditions"), v.Conditions)...)
+
+		for j, benefit := range v.Benefits {
+			benefitPath := index(join(path, "benefits"), j)
+			if strings.TrimSpace(benefit.Name) == "" {
+				errs = append(errs, fieldError(join(benefitPath, "name"), RuleRequired, nil))
 			}
+			if benefit.Amount <= 0 {
+				errs = append(errs, fieldError(join(benefitPath, "amount"), RulePositive, benefit.Amount))
+			}
+			errs = append(errs, ValidateBenefitSchedule(benefitPath, benefit.BenefitSchedule)...)
 		}
+	}
 
-		for _, benefit := range v.Benefits {
-			if benefit.Name == "" || benefit.Amount <= 0 {
-				log.Printf("Invalid benefit: %+v", benefit)
-				return false
-			}
+	return errs
+}
+
+// ValidateSchemePeriod checks the dates of a scheme, the application window must be within the effective period.
+func ValidateSchemePeriod(period models.SchemePeriod) []models.FieldError {
+	errs := []models.FieldError{}
+	dates := []struct {
+		field string
+		value *string
+	}{
+		{"effective_from", period.EffectiveFrom},
+		{"effective_to", period.EffectiveTo},
+		{"application_from", period.ApplicationFrom},
+		{"application_to", period.ApplicationTo},
+	}
+	for _, date := range dates {
+		if date.value != nil && !ValidateDate(*date.value) {
+			errs = append(errs, fieldError(date.field, RuleDate, *date.value))
 		}
 	}
+	if len(errs) > 0 {
+		return errs
+	}
 
-	return true
+	// dates are YYYY-MM-DD so they compare as strings
+	before := func(a *string, b *string) bool {
+		return a != nil && b != nil && *a < *b
+	}
+	if before(period.EffectiveTo, period.EffectiveFrom) {
+		errs = append(errs, fieldError("effective_to", RuleRange, *period.EffectiveTo))
+	}
+	if before(period.ApplicationTo, period.ApplicationFrom) {
+		errs = append(errs, fieldError("application_to", RuleRange, *period.ApplicationTo))
+	}
+	if before(period.ApplicationFrom, period.EffectiveFrom) || before(period.EffectiveTo, period.ApplicationFrom) {
+		errs = append(errs, fieldError("application_from", RuleWithin, *period.ApplicationFrom))
+	}
+	if before(period.ApplicationTo, period.EffectiveFrom) || before(period.EffectiveTo, period.ApplicationTo) {
+		errs = append(errs, fieldError("application_to", RuleWithin, *period.ApplicationTo))
+	}
+
+	return errs
 }
 
-func ValidateApplicationForm(application models.ApplicationRequest) bool {
-	if application.ApplicantID == uuid.Nil || application.SchemeID == uuid.Nil {
-		return false
+// ValidateSchemeFunding checks the budget and recipient quota of a scheme, both may be left unset.
+func ValidateSchemeFunding(funding models.SchemeFunding) []models.FieldError {
+	errs := []models.FieldError{}
+	if funding.Budget != nil && *funding.Budget <= 0 {
+		errs = append(errs, fieldError("budget", RulePositive, *funding.Budget))
 	}
-	return true
+	if funding.MaxRecipients != nil && *funding.MaxRecipients <= 0 {
+		errs = append(errs, fieldError("max_recipients", RulePositive, *funding.MaxRecipients))
+	}
+	if funding.BudgetPolicy != "" && !ValidateBudgetPolicy(funding.BudgetPolicy) {
+		errs = append(errs, fieldError("budget_policy", RuleOneOf, funding.BudgetPolicy))
+	}
+	return errs
+}
+
+// ValidateSchemeRelationships checks the relationships of a scheme, a scheme has at most one with each other scheme.
+func ValidateSchemeRelationships(relationships []models.SchemeRelationship) []models.FieldError {
+	errs := []models.FieldError{}
+	seen := make(map[uuid.UUID]bool)
+	for i, relationship := range relationships {
+		path := index("relationships", i)
+		if relationship.SchemeId == uuid.Nil {
+			errs = append(errs, fieldError(join(path, "scheme_id"), RuleRequired, nil))
+		} else if seen[relationship.SchemeId] {
+			errs = append(errs, fieldError(join(path, "scheme_id"), RuleUnique, relationship.SchemeId))
+		}
+		seen[relationship.SchemeId] = true
+
+		if !ValidateRelation(relationship.Relation) {
+			errs = append(errs, fieldError(join(path, "relation"), RuleOneOf, relationship.Relation))
+		}
+	}
+	return errs
+}
+
+// ValidateBenefitSchedule checks how a benefit is paid, path is the JSON path of the benefit. Left out
+// fields default to a single instalment paid on approval.
+func ValidateBenefitSchedule(path string, schedule models.BenefitSchedule) []models.FieldError {
+	errs := []models.FieldError{}
+	if schedule.Frequency != "" && !ValidateFrequency(schedule.Frequency) {
+		errs = append(errs, fieldError(join(path, "frequency"), RuleOneOf, schedule.Frequency))
+	}
+	if schedule.Instalments < 0 {
+		errs = append(errs, fieldError(join(path, "instalments"), RulePositive, schedule.Instalments))
+	} else if schedule.Instalments > 1 && (schedule.Frequency == "" || strings.ToLower(schedule.Frequency) == config.FrequencyOneOff) {
+		// a one-off benefit is paid once
+		errs = append(errs, fieldError(join(path, "instalments"), RuleOneOf, schedule.Instalments))
+	}
+	if schedule.StartRule != "" && !ValidateStartRule(schedule.StartRule) {
+		errs = append(errs, fieldError(join(path, "start_rule"), RuleOneOf, schedule.StartRule))
+	}
+	if strings.ToLower(schedule.StartRule) == config.StartFixedDate {
+		if schedule.StartDate == nil {
+			errs = append(errs, fieldError(join(path, "start_date"), RuleRequired, nil))
+		} else if !ValidateDate(*schedule.StartDate) {
+			errs = append(errs, fieldError(join(path, "start_date"), RuleDate, *schedule.StartDate))
+		}
+	}
+	return errs
+}
+
+// ValidateRule checks a rule tree, path is the JSON path of the rule in the request.
+func ValidateRule(path string, rule eligibility.Rule) []models.FieldError {
+	switch {
+	case rule.All != nil || rule.Any != nil:
+		group, rules := "all", rule.All
+		if rule.Any != nil {
+			group, rules = "any", rule.Any
+		}
+
+		if len(rules) == 0 {
+			return []models.FieldError{fieldError(join(path, group), RuleNotEmpty, nil)}
+		}
+
+		errs := []models.FieldError{}
+		for i, r := range rules {
+			errs = append(errs, ValidateRule(index(join(path, group), i), r)...)
+		}
+		return errs
+	case rule.Not != nil:
+		return ValidateRule(join(path, "not"), *rule.Not)
+	}
+
+	if len(rule.Conditions) == 0 {
+		return []models.FieldError{fieldError(path, RuleNotEmpty, nil)}
+	}
+
+	// sorted so the errors come back in the same order every time
+	keys := make([]string, 0, len(rule.Conditions))
+	for key := range rule.Conditions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errs := []models.FieldError{}
+	for _, key := range keys {
+		errs = append(errs, ValidateCondition(join(path, key), key, rule.Conditions[key])...)
+	}
+
+	return errs
+}
+
+// ValidateCondition checks a single condition of a rule, path is the JSON path of the condition.
+func ValidateCondition(path string, key string, value interface{}) []models.FieldError {
+	if !eligibility.IsConditionKey(key) {
+		return []models.FieldError{fieldError(path, RuleUnknownKey, key)}
+	}
+
+	switch key {
+	case "employment_status":
+		if employmentStatus, ok := value.(string); !ok || !ValidateEmploymentStatus(employmentStatus) {
+			return []models.FieldError{fieldError(path, RuleOneOf, value)}
+		}
+	case "has_children":
+		hasChildren, ok := value.(map[string]interface{})
+		if !ok {
+			return []models.FieldError{fieldError(path, RuleType, value)}
+		}
+
+		errs := []models.FieldError{}
+		// only recognize school_level for now
+		if schoolLevel, ok := hasChildren["school_level"].(string); !ok {
+			errs = append(errs, fieldError(join(path, "school_level"), RuleRequired, nil))
+		} else if !ValidateSchool(schoolLevel) {
+			errs = append(errs, fieldError(join(path, "school_level"), RuleFormat, schoolLevel))
+		}
+
+		if basis, ok := hasChildren["age_basis"]; ok && !ValidateAgeBasis(basis) {
+			errs = append(errs, fieldError(join(path, "age_basis"), RuleOneOf, basis))
+		}
+		return errs
+	case "marital_status":
+		if maritalStatus, ok := value.(string); !ok || !ValidateMaritalStatus(maritalStatus) {
+			return []models.FieldError{fieldError(path, RuleOneOf, value)}
+		}
+	case "sex":
+		if sex, ok := value.(string); !ok || !ValidateSex(sex) {
+			return []models.FieldError{fieldError(path, RuleOneOf, value)}
+		}
+	case "age", "household_size", "monthly_household_income":
+		if _, basis, err := eligibility.ParseNumericCondition(value); err != nil {
+			log.Printf("Invalid %s criteria: %v", key, err)
+			return []models.FieldError{fieldError(path, RuleFormat, value)}
+		} else if basis != "" && key != "age" {
+			// age_basis is only supported for age criteria
+			return []models.FieldError{fieldError(join(path, "age_basis"), RuleUnknownKey, basis)}
+		}
+	}
+
+	return nil
+}
+
+func ValidateApplicationForm(application models.ApplicationRequest) []models.FieldError {
+	errs := []models.FieldError{}
+	if application.ApplicantID == uuid.Nil {
+		errs = append(errs, fieldError("applicant_id", RuleRequired, nil))
+	}
+	if application.SchemeID == uuid.Nil {
+		errs = append(errs, fieldError("scheme_id", RuleRequired, nil))
+	}
+	return errs
 }